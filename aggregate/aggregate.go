@@ -0,0 +1,97 @@
+// Package aggregate summarises the documents in EZ DB iterators.
+// Documents are read one at a time rather than all at once, so summarising a LevelDB collection does not require loading it into memory.
+package aggregate
+
+import "github.com/annybs/ezdb"
+
+// Number is a constraint for types that can be summed.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// CountBy counts documents in groups.
+// The function f returns the group of each document.
+//
+// Documents are read one at a time, and the iterator is released once all documents have been read.
+func CountBy[T any, K comparable](i ezdb.Iterator[T], f func(key string, value T) K) (map[K]int, error) {
+	return Reduce(i, map[K]int{}, func(m map[K]int, key string, value T) map[K]int {
+		m[f(key, value)]++
+		return m
+	})
+}
+
+// GroupBy collects documents in groups.
+// The function f returns the group of each document.
+// Within each group, documents are in iterator order.
+//
+// Documents are read one at a time, and the iterator is released once all documents have been read.
+func GroupBy[T any, K comparable](i ezdb.Iterator[T], f func(key string, value T) K) (map[K][]T, error) {
+	return Reduce(i, map[K][]T{}, func(m map[K][]T, key string, value T) map[K][]T {
+		group := f(key, value)
+		m[group] = append(m[group], value)
+		return m
+	})
+}
+
+// Max gets the document that sorts last according to f.
+// If more than one document sorts last, the first of them is returned.
+// If there are no documents, ezdb.ErrNotFound is returned.
+//
+// Documents are read one at a time, and the iterator is released once all documents have been read.
+func Max[T any](i ezdb.Iterator[T], f ezdb.SortFunc[T]) (string, T, error) {
+	return Min(i, func(a, b T) bool {
+		return f(b, a)
+	})
+}
+
+// Min gets the document that sorts first according to f.
+// If more than one document sorts first, the first of them is returned.
+// If there are no documents, ezdb.ErrNotFound is returned.
+//
+// Documents are read one at a time, and the iterator is released once all documents have been read.
+func Min[T any](i ezdb.Iterator[T], f ezdb.SortFunc[T]) (string, T, error) {
+	found := false
+	var minKey string
+	var minValue T
+
+	for key, value := range i.All() {
+		if !found || f(value, minValue) {
+			found = true
+			minKey = key
+			minValue = value
+		}
+	}
+
+	if err := i.Err(); err != nil {
+		return minKey, minValue, err
+	}
+	if !found {
+		return minKey, minValue, ezdb.ErrNotFound
+	}
+	return minKey, minValue, nil
+}
+
+// Reduce combines documents into a single value.
+// The function f is called for each document with the value accumulated so far, starting with init, and returns the new accumulated value.
+//
+// Documents are read one at a time, and the iterator is released once all documents have been read.
+// If a document cannot be read, the value accumulated so far is returned with the error.
+func Reduce[T any, A any](i ezdb.Iterator[T], init A, f func(acc A, key string, value T) A) (A, error) {
+	acc := init
+	for key, value := range i.All() {
+		acc = f(acc, key, value)
+	}
+	return acc, i.Err()
+}
+
+// Sum adds up a number from each document.
+// The function f returns the number for each document.
+//
+// Documents are read one at a time, and the iterator is released once all documents have been read.
+func Sum[T any, N Number](i ezdb.Iterator[T], f func(key string, value T) N) (N, error) {
+	return Reduce(i, 0, func(sum N, key string, value T) N {
+		return sum + f(key, value)
+	})
+}
@@ -0,0 +1,98 @@
+package aggregate
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/annybs/ezdb"
+)
+
+// Basic struct for testing.
+type Student struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// Sample data.
+var students = map[string]*Student{
+	"annie": {Name: "Annie", Age: 32},
+	"ben":   {Name: "Ben", Age: 50},
+	"clive": {Name: "Clive", Age: 21},
+}
+
+func over30(key string, value *Student) bool {
+	return value.Age > 30
+}
+
+func TestAggregateLevelDB(t *testing.T) {
+	c := ezdb.LevelDB(filepath.Join(t.TempDir(), "aggregate_test"), ezdb.JSON(func() *Student {
+		return &Student{}
+	}), nil)
+	defer c.Destroy()
+
+	testAggregate(t, c)
+}
+
+func TestAggregateMemory(t *testing.T) {
+	testAggregate(t, ezdb.Memory[*Student](nil))
+}
+
+func testAggregate(t *testing.T, c ezdb.Collection[*Student]) {
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	byAge := ezdb.By(func(s *Student) int { return s.Age }).SortFunc()
+
+	counts, err := CountBy(c.Iter(), over30)
+	if err != nil {
+		t.Errorf("failed to count students (%q)", err)
+	} else if counts[true] != 2 || counts[false] != 1 {
+		t.Errorf("incorrect count of students (expected 2 over 30 and 1 not, got %v)", counts)
+	}
+
+	groups, err := GroupBy(c.Iter().SortKeys(func(a, b string) bool { return a < b }), over30)
+	if err != nil {
+		t.Errorf("failed to group students (%q)", err)
+	} else if len(groups[true]) != 2 || groups[true][0].Name != "Annie" || groups[true][1].Name != "Ben" {
+		t.Errorf("incorrect group of students over 30 (got %v)", groups[true])
+	}
+
+	sum, err := Sum(c.Iter(), func(key string, value *Student) int { return value.Age })
+	if err != nil {
+		t.Errorf("failed to sum ages (%q)", err)
+	} else if sum != 103 {
+		t.Errorf("incorrect sum of ages (expected 103, got %d)", sum)
+	}
+
+	if key, _, err := Min(c.Iter(), byAge); err != nil {
+		t.Errorf("failed to get youngest student (%q)", err)
+	} else if key != "clive" {
+		t.Errorf("incorrect youngest student (expected 'clive', got '%s')", key)
+	}
+
+	if key, _, err := Max(c.Iter(), byAge); err != nil {
+		t.Errorf("failed to get oldest student (%q)", err)
+	} else if key != "ben" {
+		t.Errorf("incorrect oldest student (expected 'ben', got '%s')", key)
+	}
+
+	if _, _, err := Min(c.Iter().Prefix("nobody"), byAge); !errors.Is(err, ezdb.ErrNotFound) {
+		t.Errorf("expected not found error for youngest of no students, got %v", err)
+	}
+
+	names, err := Reduce(c.Iter().SortKeys(func(a, b string) bool { return a < b }), "", func(acc string, key string, value *Student) string {
+		return acc + value.Name[:1]
+	})
+	if err != nil {
+		t.Errorf("failed to reduce students (%q)", err)
+	} else if names != "ABC" {
+		t.Errorf("incorrect reduction of students (expected 'ABC', got '%s')", names)
+	}
+}
@@ -0,0 +1,25 @@
+package ezdb
+
+import "github.com/fxamacker/cbor/v2"
+
+// CBORMarshaler is a DocumentMarshaler that converts documents to CBOR data.
+type CBORMarshaler[T any] struct {
+	factory func() T
+}
+
+func (m *CBORMarshaler[T]) Factory() T {
+	return m.factory()
+}
+
+func (m *CBORMarshaler[T]) Marshal(src T) ([]byte, error) {
+	return cbor.Marshal(src)
+}
+
+func (m *CBORMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	return cbor.Unmarshal(src, dest)
+}
+
+// CBOR creates a DocumentMarshaler that converts documents to CBOR data.
+func CBOR[T any](factory func() T) *CBORMarshaler[T] {
+	return &CBORMarshaler[T]{factory: factory}
+}
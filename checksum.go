@@ -0,0 +1,59 @@
+package ezdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Header prepended to checksummed values, followed by a 4-byte CRC-32C of the remaining data.
+var checksumHeader = []byte("\x00ezk")
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumMarshaler is a DocumentMarshaler that verifies the integrity of the output of another DocumentMarshaler.
+type ChecksumMarshaler[T any] struct {
+	m DocumentMarshaler[T, []byte]
+}
+
+func (m *ChecksumMarshaler[T]) Factory() T {
+	return m.m.Factory()
+}
+
+func (m *ChecksumMarshaler[T]) Marshal(src T) ([]byte, error) {
+	raw, err := m.m.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := make([]byte, 0, len(checksumHeader)+4+len(raw))
+	dest = append(dest, checksumHeader...)
+	dest = binary.BigEndian.AppendUint32(dest, crc32.Checksum(raw, crc32c))
+	dest = append(dest, raw...)
+	return dest, nil
+}
+
+func (m *ChecksumMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	if !bytes.HasPrefix(src, checksumHeader) {
+		return m.m.Unmarshal(src, dest)
+	}
+
+	if len(src) < len(checksumHeader)+4 {
+		return ErrChecksum
+	}
+	sum := binary.BigEndian.Uint32(src[len(checksumHeader):])
+	raw := src[len(checksumHeader)+4:]
+	if crc32.Checksum(raw, crc32c) != sum {
+		return ErrChecksum
+	}
+
+	return m.m.Unmarshal(raw, dest)
+}
+
+// Checksummed creates a DocumentMarshaler that stores a CRC-32C checksum with each document marshaled by m, and verifies it when the document is read.
+//
+// Values without a checksum, such as those written before checksums were enabled, are passed to m unchanged.
+// Use Scrub to find documents that fail verification.
+func Checksummed[T any](m DocumentMarshaler[T, []byte]) *ChecksumMarshaler[T] {
+	return &ChecksumMarshaler[T]{m: m}
+}
@@ -0,0 +1,88 @@
+package ezdb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChecksumRoundTrip(t *testing.T) {
+	m := Checksummed(studentMarshaler)
+
+	for key, value := range students {
+		b, err := m.Marshal(value)
+		if err != nil {
+			t.Errorf("failed to marshal student '%s' (%q)", key, err)
+			continue
+		}
+
+		actual := m.Factory()
+		if err := m.Unmarshal(b, actual); err != nil {
+			t.Errorf("failed to unmarshal student '%s' (%q)", key, err)
+		} else if err := compareStudent(key, value, actual); err != nil {
+			t.Error(err)
+		}
+
+		b[len(b)-2] ^= 0xff
+		if err := m.Unmarshal(b, m.Factory()); !errors.Is(err, ErrChecksum) {
+			t.Errorf("expected checksum error for corrupted student '%s', got %v", key, err)
+		}
+	}
+}
+
+func TestScrub(t *testing.T) {
+	path := ".leveldb/scrub_test"
+	c := LevelDB[*Student](path, Checksummed(studentMarshaler), nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	if err := c.Unique("name", studentName); err != nil {
+		t.Fatalf("failed to add unique constraint (%q)", err)
+	}
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	// Corrupt one student
+	b, _ := c.db.Get([]byte("ben"), nil)
+	b[len(b)-2] ^= 0xff
+	if err := c.db.Put([]byte("ben"), b, nil); err != nil {
+		t.Fatalf("failed to corrupt student 'ben' (%q)", err)
+	}
+
+	quarantine := Memory[[]byte](nil)
+	quarantine.Open()
+
+	r, err := c.Scrub(quarantine)
+	if err != nil {
+		t.Fatalf("failed to scrub collection (%q)", err)
+	}
+	if r.Checked != len(students) {
+		t.Errorf("incorrect number of students checked (expected %d, got %d)", len(students), r.Checked)
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Key != "ben" || !errors.Is(r.Errors[0], ErrChecksum) {
+		t.Errorf("expected checksum error for student 'ben', got %v", r.Errors)
+	}
+	if r.Quarantined != 1 {
+		t.Errorf("expected 1 student to be quarantined, got %d", r.Quarantined)
+	}
+
+	if has, _ := quarantine.Has("ben"); !has {
+		t.Error("expected quarantine to have student 'ben'")
+	}
+	if has, _ := c.Has("ben"); has {
+		t.Error("expected collection not to have quarantined student 'ben'")
+	}
+
+	// The quarantined student's unique index entries are deleted with it
+	if _, err := c.db.Get(uniqueIndexKey("name", students["ben"].Name), nil); err == nil {
+		t.Error("expected unique index entry of quarantined student 'ben' to be deleted")
+	}
+	if err := c.Put("benjamin", &Student{Name: students["ben"].Name, Age: 20}); err != nil {
+		t.Errorf("failed to reuse name of quarantined student (%q)", err)
+	}
+}
@@ -3,6 +3,7 @@ package ezdb
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -226,6 +227,102 @@ func (c *CollectionTest) iterLast() error {
 	return nil
 }
 
+func (c *CollectionTest) iterAll() error {
+	// Test collection can range over all students
+	n := 0
+	for key, actual := range c.C.All() {
+		if err := compareStudent(key, students[key], actual); err != nil {
+			c.T.Errorf("(iterAll) %v", err)
+		}
+		n++
+	}
+	if n != len(students) {
+		c.T.Errorf("(iterAll) incorrect count of students (expected %d, got %d)", len(students), n)
+		return errors.New("incorrect count")
+	}
+
+	// Test iteration can be stopped early
+	n = 0
+	for range c.C.Keys() {
+		n++
+		break
+	}
+	if n != 1 {
+		c.T.Errorf("(iterAll) expected iteration to stop after 1 student, got %d", n)
+	}
+
+	// Test results include every student without errors
+	n = 0
+	for key, result := range c.C.Results() {
+		if result.Err != nil {
+			c.T.Errorf("(iterAll) failed to read student '%s': %v", key, result.Err)
+		}
+		n++
+	}
+	if n != len(students) {
+		c.T.Errorf("(iterAll) incorrect count of results (expected %d, got %d)", len(students), n)
+	}
+
+	// Test iterator can range over sorted values
+	iter := c.C.Iter().SortKeys(func(a, b string) bool {
+		return a < b
+	})
+	names := []string{}
+	for value := range iter.Values() {
+		names = append(names, value.Name)
+	}
+	if err := iter.Err(); err != nil {
+		c.T.Errorf("(iterAll) failed to range over values: %v", err)
+		return err
+	}
+	if len(names) != 3 || names[0] != "Annie" || names[2] != "Clive" {
+		c.T.Errorf("(iterAll) incorrect sorted values (expected [Annie Ben Clive], got %v)", names)
+	} else {
+		c.T.Logf("(iterAll) ranged over sorted values %v", names)
+	}
+
+	return nil
+}
+
+func (c *CollectionTest) iterWindow() error {
+	testIterWindow(c.T, func() Iterator[*Student] {
+		return c.C.Iter().SortKeys(func(a, b string) bool {
+			return a < b
+		})
+	})
+
+	return nil
+}
+
+func (c *CollectionTest) iterSort() error {
+	byAge := By(func(s *Student) int { return s.Age })
+
+	tests := map[string]struct {
+		Iter     Iterator[*Student]
+		Expected string
+	}{
+		"sort":       {c.C.Iter().Sort(byAge.SortFunc()), "clive,annie,ben"},
+		"sort desc":  {c.C.Iter().Sort(byAge.Desc().SortFunc()), "ben,annie,clive"},
+		"top":        {c.C.Iter().TopK(2, byAge.SortFunc()), "clive,annie"},
+		"top desc":   {c.C.Iter().TopK(1, byAge.Desc().SortFunc()), "ben"},
+		"top all":    {c.C.Iter().TopK(5, byAge.SortFunc()), "clive,annie,ben"},
+		"top none":   {c.C.Iter().TopK(0, byAge.SortFunc()), ""},
+		"top filter": {c.C.Iter().Prefix("a").TopK(2, byAge.SortFunc()), "annie"},
+	}
+
+	for name, test := range tests {
+		actual := strings.Join(collectKeys(test.Iter), ",")
+		if actual != test.Expected {
+			c.T.Errorf("(iterSort) %s: incorrect students (expected '%s', got '%s')", name, test.Expected, actual)
+		} else {
+			c.T.Logf("(iterSort) %s: correct students '%s'", name, actual)
+		}
+		test.Iter.Release()
+	}
+
+	return nil
+}
+
 func (c *CollectionTest) close() error {
 	if c.F["close"] != nil {
 		if err := c.F["close"](); err != nil {
@@ -252,6 +349,9 @@ func (c *CollectionTest) Run() {
 		c.iterCount,
 		c.iterFirst,
 		c.iterLast,
+		c.iterAll,
+		c.iterWindow,
+		c.iterSort,
 		c.close,
 	}
 
@@ -262,6 +362,126 @@ func (c *CollectionTest) Run() {
 	}
 }
 
+// newStudentCollection creates an open in-memory collection of sample data.
+func newStudentCollection(t *testing.T) *MemoryCollection[*Student] {
+	c := Memory[*Student](nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+	return c
+}
+
+// testIterWindow tests seeking and windowing of iterators created by sorted, which must be sorted by key.
+func testIterWindow(t *testing.T, sorted func() Iterator[*Student]) {
+	tests := map[string]struct {
+		Iter     Iterator[*Student]
+		Expected string
+	}{
+		"skip":          {sorted().Skip(1), "ben,clive"},
+		"limit":         {sorted().Limit(2), "annie,ben"},
+		"skip limit":    {sorted().Skip(1).Limit(1), "ben"},
+		"limit zero":    {sorted().Limit(0), ""},
+		"reverse":       {sorted().Reverse(), "clive,ben,annie"},
+		"reverse skip":  {sorted().Reverse().Skip(1), "ben,annie"},
+		"skip reverse":  {sorted().Skip(1).Reverse(), "clive,ben"},
+		"limit reverse": {sorted().Limit(2).Reverse(), "ben,annie"},
+		"filter":        {sorted().Filter(func(key string, value *Student) bool { return value.Age > 30 }), "annie,ben"},
+		"key filter":    {sorted().KeyFilter(func(key string) bool { return key != "ben" }), "annie,clive"},
+		"filter skip":   {sorted().KeyFilter(func(key string) bool { return key != "ben" }).Skip(1), "clive"},
+		"skip filter":   {sorted().Skip(1).KeyFilter(func(key string) bool { return key != "ben" }), "clive"},
+		"prefix":        {sorted().Prefix("b"), "ben"},
+		"skip prefix":   {sorted().Skip(1).Prefix("c"), "clive"},
+		"prefix none":   {sorted().Prefix("d"), ""},
+	}
+
+	for name, test := range tests {
+		actual := strings.Join(collectKeys(test.Iter), ",")
+		if actual != test.Expected {
+			t.Errorf("(iterWindow) %s: incorrect students (expected '%s', got '%s')", name, test.Expected, actual)
+		} else {
+			t.Logf("(iterWindow) %s: correct students '%s'", name, actual)
+		}
+
+		// Last and Prev must stay within the window
+		if test.Expected != "" {
+			keys := strings.Split(test.Expected, ",")
+			if !test.Iter.Last() || test.Iter.Key() != keys[len(keys)-1] {
+				t.Errorf("(iterWindow) %s: incorrect last student (expected '%s', got '%s')", name, keys[len(keys)-1], test.Iter.Key())
+			}
+			for n := len(keys) - 2; n >= 0; n-- {
+				if !test.Iter.Prev() || test.Iter.Key() != keys[n] {
+					t.Errorf("(iterWindow) %s: incorrect previous student (expected '%s', got '%s')", name, keys[n], test.Iter.Key())
+				}
+			}
+			if test.Iter.Prev() {
+				t.Errorf("(iterWindow) %s: expected no student before '%s', got '%s'", name, keys[0], test.Iter.Key())
+			}
+		}
+
+		test.Iter.Release()
+	}
+
+	// Test seeking
+	iter := sorted()
+	defer iter.Release()
+	if !iter.Seek("b") || iter.Key() != "ben" {
+		t.Errorf("(iterWindow) incorrect student after seek (expected 'ben', got '%s')", iter.Key())
+	} else if !iter.Next() || iter.Key() != "clive" {
+		t.Errorf("(iterWindow) incorrect student after seek and next (expected 'clive', got '%s')", iter.Key())
+	}
+	if iter.Seek("d") {
+		t.Errorf("(iterWindow) expected no student after seeking past the end, got '%s'", iter.Key())
+	}
+
+	reversed := sorted().Reverse()
+	defer reversed.Release()
+	if !reversed.Seek("bz") || reversed.Key() != "ben" {
+		t.Errorf("(iterWindow) incorrect student after reverse seek (expected 'ben', got '%s')", reversed.Key())
+	}
+
+	// A failed seek leaves a windowed iterator past the end of its window
+	window := sorted().Skip(1).Limit(1)
+	defer window.Release()
+	if window.Seek("c") {
+		t.Errorf("(iterWindow) expected no student after seeking past the window, got '%s'", window.Key())
+	}
+	if key := window.Key(); key != "" {
+		t.Errorf("(iterWindow) expected no key after seeking past the window, got '%s'", key)
+	}
+	if value, _ := window.Value(); value != nil {
+		t.Errorf("(iterWindow) expected no student after seeking past the window, got %v", value)
+	}
+
+	// Moving past the end of a window repeatedly must not move the window
+	drift := sorted().Skip(1).Limit(10)
+	defer drift.Release()
+	for ok := drift.First(); ok; ok = drift.Next() {
+	}
+	for n := 0; n < 3; n++ {
+		drift.Next()
+	}
+	keys := []string{}
+	for drift.Prev() {
+		keys = append(keys, drift.Key())
+	}
+	if actual := strings.Join(keys, ","); actual != "clive,ben" {
+		t.Errorf("(iterWindow) incorrect students moving back from past the end (expected 'clive,ben', got '%s')", actual)
+	}
+}
+
+func collectKeys[T any](iter Iterator[T]) []string {
+	keys := []string{}
+	for ok := iter.First(); ok; ok = iter.Next() {
+		keys = append(keys, iter.Key())
+	}
+	return keys
+}
+
 func compareStudent(expectedKey string, expected, actual *Student) error {
 	if actual.Name != expected.Name {
 		return fmt.Errorf("student '%s' has wrong name (expected '%s', got '%s')", expectedKey, expected.Name, actual.Name)
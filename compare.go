@@ -0,0 +1,72 @@
+package ezdb
+
+import "cmp"
+
+// Comparator compares documents by one or more fields, in order of priority.
+// Use By to create a Comparator, and SortFunc to use it with Sort or TopK.
+//
+// Comparators are immutable; each method returns a new Comparator.
+type Comparator[T any] struct {
+	fields []compareField[T]
+}
+
+type compareField[T any] struct {
+	cmp  func(a, b T) int
+	desc bool
+}
+
+// Compare two documents.
+// Returns a negative number if a sorts before b, a positive number if a sorts after b, or zero if they are equal in every field.
+func (c *Comparator[T]) Compare(a, b T) int {
+	for _, field := range c.fields {
+		n := field.cmp(a, b)
+		if field.desc {
+			n = -n
+		}
+		if n != 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// Desc reverses the order of the last field added to the Comparator.
+func (c *Comparator[T]) Desc() *Comparator[T] {
+	fields := append([]compareField[T]{}, c.fields...)
+	if len(fields) > 0 {
+		fields[len(fields)-1].desc = !fields[len(fields)-1].desc
+	}
+	return &Comparator[T]{fields: fields}
+}
+
+// SortFunc creates a SortFunc using the Comparator.
+func (c *Comparator[T]) SortFunc() SortFunc[T] {
+	return func(a, b T) bool {
+		return c.Compare(a, b) < 0
+	}
+}
+
+// Then adds the fields of another Comparator, which are used to compare documents that are equal in every field of this one.
+func (c *Comparator[T]) Then(next *Comparator[T]) *Comparator[T] {
+	fields := append([]compareField[T]{}, c.fields...)
+	fields = append(fields, next.fields...)
+	return &Comparator[T]{fields: fields}
+}
+
+// By creates a Comparator that compares documents by an ordered field.
+//
+// For example, to sort students by descending age then by name:
+//
+//	By(func(s *Student) int { return s.Age }).Desc().Then(By(func(s *Student) string { return s.Name }))
+func By[T any, V cmp.Ordered](field func(T) V) *Comparator[T] {
+	return ByFunc(func(a, b T) int {
+		return cmp.Compare(field(a), field(b))
+	})
+}
+
+// ByFunc creates a Comparator that compares documents using a function, which follows the same convention as Comparator.Compare.
+func ByFunc[T any](f func(a, b T) int) *Comparator[T] {
+	return &Comparator[T]{
+		fields: []compareField[T]{{cmp: f}},
+	}
+}
@@ -0,0 +1,42 @@
+package ezdb
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestComparator(t *testing.T) {
+	a := []*Student{
+		{Name: "Clive", Age: 21},
+		{Name: "Ben", Age: 50},
+		{Name: "Annie", Age: 32},
+		{Name: "Bella", Age: 50},
+	}
+
+	byAge := By(func(s *Student) int { return s.Age })
+	byName := By(func(s *Student) string { return s.Name })
+
+	tests := map[string]struct {
+		C        *Comparator[*Student]
+		Expected []string
+	}{
+		"age":           {byAge, []string{"Clive", "Annie", "Ben", "Bella"}},
+		"age name":      {byAge.Then(byName), []string{"Clive", "Annie", "Bella", "Ben"}},
+		"age desc name": {byAge.Desc().Then(byName), []string{"Bella", "Ben", "Annie", "Clive"}},
+		"age name desc": {byAge.Then(byName).Desc(), []string{"Clive", "Annie", "Ben", "Bella"}},
+	}
+
+	for name, test := range tests {
+		f := test.C.SortFunc()
+		sorted := append([]*Student{}, a...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return f(sorted[i], sorted[j])
+		})
+
+		for n, s := range sorted {
+			if s.Name != test.Expected[n] {
+				t.Errorf("%s: incorrect student at position %d (expected '%s', got '%s')", name, n, test.Expected[n], s.Name)
+			}
+		}
+	}
+}
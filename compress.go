@@ -0,0 +1,216 @@
+package ezdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression codec IDs used by the codecs included in EZ DB.
+// Custom codecs should use IDs outside of this range.
+const (
+	CodecSnappy byte = 1
+	CodecGzip   byte = 2
+	CodecZstd   byte = 3
+)
+
+// Header prepended to compressed values.
+// JSON and most other encodings cannot begin with a null byte, which allows uncompressed values to be read as-is.
+var compressedHeader = []byte("\x00ezc")
+
+// CompressedMarshaler is a DocumentMarshaler that compresses the output of another DocumentMarshaler.
+type CompressedMarshaler[T any] struct {
+	m     DocumentMarshaler[T, []byte]
+	codec CompressionCodec
+}
+
+// CompressionCodec compresses and decompresses data for a CompressedMarshaler.
+type CompressionCodec interface {
+	ID() byte // Get the ID stored in the header of compressed values.
+
+	Compress(src []byte) ([]byte, error)   // Compress data.
+	Decompress(src []byte) ([]byte, error) // Decompress data.
+}
+
+func (m *CompressedMarshaler[T]) Factory() T {
+	return m.m.Factory()
+}
+
+func (m *CompressedMarshaler[T]) Marshal(src T) ([]byte, error) {
+	raw, err := m.m.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := m.codec.Compress(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store small or incompressible values as-is, unless they could be mistaken for a compressed value
+	if len(data)+len(compressedHeader)+1 >= len(raw) && !bytes.HasPrefix(raw, compressedHeader) {
+		return raw, nil
+	}
+
+	dest := make([]byte, 0, len(compressedHeader)+1+len(data))
+	dest = append(dest, compressedHeader...)
+	dest = append(dest, m.codec.ID())
+	dest = append(dest, data...)
+	return dest, nil
+}
+
+func (m *CompressedMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	if !bytes.HasPrefix(src, compressedHeader) || len(src) <= len(compressedHeader) {
+		return m.m.Unmarshal(src, dest)
+	}
+
+	id := src[len(compressedHeader)]
+	codec := m.codec
+	if id != codec.ID() {
+		codec = builtinCodec(id)
+		if codec == nil {
+			return ErrUnknownCodec
+		}
+	}
+
+	raw, err := codec.Decompress(src[len(compressedHeader)+1:])
+	if err != nil {
+		return err
+	}
+
+	return m.m.Unmarshal(raw, dest)
+}
+
+// Compressed creates a DocumentMarshaler that compresses the output of another DocumentMarshaler m.
+//
+// Values are written with a small header identifying the codec.
+// Values without the header, such as those written before compression was enabled, are passed to m unchanged.
+// Values compressed by any other codec included in EZ DB can also be read, so the codec can be changed at any time.
+func Compressed[T any](m DocumentMarshaler[T, []byte], codec CompressionCodec) *CompressedMarshaler[T] {
+	return &CompressedMarshaler[T]{
+		m:     m,
+		codec: codec,
+	}
+}
+
+type gzipCodec struct {
+	level int
+}
+
+func (c *gzipCodec) ID() byte {
+	return CodecGzip
+}
+
+func (c *gzipCodec) Compress(src []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w, err := gzip.NewWriterLevel(buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCodec) Decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Gzip creates a CompressionCodec using gzip at the given compression level.
+// See compress/gzip for valid levels.
+func Gzip(level int) CompressionCodec {
+	return &gzipCodec{level: level}
+}
+
+type snappyCodec struct{}
+
+func (c *snappyCodec) ID() byte {
+	return CodecSnappy
+}
+
+func (c *snappyCodec) Compress(src []byte) ([]byte, error) {
+	return snappy.Encode(nil, src), nil
+}
+
+func (c *snappyCodec) Decompress(src []byte) ([]byte, error) {
+	return snappy.Decode(nil, src)
+}
+
+// Snappy creates a CompressionCodec using Snappy.
+func Snappy() CompressionCodec {
+	return &snappyCodec{}
+}
+
+type zstdCodec struct {
+	init sync.Once
+	err  error
+
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func (c *zstdCodec) ID() byte {
+	return CodecZstd
+}
+
+func (c *zstdCodec) Compress(src []byte) ([]byte, error) {
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	return c.enc.EncodeAll(src, nil), nil
+}
+
+func (c *zstdCodec) Decompress(src []byte) ([]byte, error) {
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+	return c.dec.DecodeAll(src, nil)
+}
+
+func (c *zstdCodec) open() error {
+	c.init.Do(func() {
+		c.enc, c.err = zstd.NewWriter(nil)
+		if c.err != nil {
+			return
+		}
+		c.dec, c.err = zstd.NewReader(nil)
+	})
+	return c.err
+}
+
+// Zstd creates a CompressionCodec using Zstandard.
+// The encoder and decoder are created on first use and shared by all documents.
+func Zstd() CompressionCodec {
+	return &zstdCodec{}
+}
+
+var (
+	builtinGzip   = Gzip(gzip.DefaultCompression)
+	builtinSnappy = Snappy()
+	builtinZstd   = Zstd()
+)
+
+func builtinCodec(id byte) CompressionCodec {
+	switch id {
+	case CodecGzip:
+		return builtinGzip
+	case CodecSnappy:
+		return builtinSnappy
+	case CodecZstd:
+		return builtinZstd
+	}
+	return nil
+}
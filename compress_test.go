@@ -0,0 +1,70 @@
+package ezdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var compressionCodecs = map[string]CompressionCodec{
+	"gzip":   Gzip(9),
+	"snappy": Snappy(),
+	"zstd":   Zstd(),
+}
+
+func TestCompressedRoundTrip(t *testing.T) {
+	large := &Student{Name: strings.Repeat("Annie ", 100), Age: 32}
+
+	for name, codec := range compressionCodecs {
+		m := Compressed(studentMarshaler, codec)
+
+		t.Logf("(%s) marshaling large student", name)
+		b, err := m.Marshal(large)
+		if err != nil {
+			t.Errorf("(%s) failed to marshal large student (%q)", name, err)
+			continue
+		}
+		if !bytes.HasPrefix(b, compressedHeader) {
+			t.Errorf("(%s) large student was not compressed", name)
+		}
+
+		actual := m.Factory()
+		if err := m.Unmarshal(b, actual); err != nil {
+			t.Errorf("(%s) failed to unmarshal large student (%q)", name, err)
+		} else if err := compareStudent("large", large, actual); err != nil {
+			t.Errorf("(%s) %v", name, err)
+		}
+
+		// Any other codec should be able to read the value
+		for otherName, otherCodec := range compressionCodecs {
+			other := Compressed(studentMarshaler, otherCodec)
+			actual := other.Factory()
+			if err := other.Unmarshal(b, actual); err != nil {
+				t.Errorf("(%s) failed to unmarshal large student with %s (%q)", name, otherName, err)
+			}
+		}
+	}
+}
+
+func TestCompressedLegacy(t *testing.T) {
+	m := Compressed(studentMarshaler, Snappy())
+
+	for key, b := range studentsMarshaled {
+		t.Logf("unmarshaling uncompressed student '%s'", key)
+		actual := m.Factory()
+		if err := m.Unmarshal(b, actual); err != nil {
+			t.Errorf("failed to unmarshal uncompressed student '%s' (%q)", key, err)
+		} else if err := compareStudent(key, students[key], actual); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCompressedUnknownCodec(t *testing.T) {
+	m := Compressed(studentMarshaler, Snappy())
+
+	b := append(append([]byte{}, compressedHeader...), 0xff, 0x00)
+	if err := m.Unmarshal(b, m.Factory()); err != ErrUnknownCodec {
+		t.Errorf("expected unknown codec error, got %v", err)
+	}
+}
@@ -0,0 +1,341 @@
+package ezdb
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Header prepended to encrypted values.
+var encryptedHeader = []byte("\x00eze")
+
+// Version of the encrypted value format, stored after the header.
+const encryptedVersion byte = 1
+
+// Number of documents re-encrypted per write batch by Rekey.
+const rekeyBatchSize = 1000
+
+// EncryptedMarshaler is a DocumentMarshaler that encrypts the output of another DocumentMarshaler using AES-GCM.
+type EncryptedMarshaler[T any] struct {
+	m    DocumentMarshaler[T, []byte]
+	keys Keyring
+
+	allowUnencrypted bool
+}
+
+// Keyring provides encryption keys to an EncryptedMarshaler.
+// Keys must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256 respectively.
+type Keyring interface {
+	Current() (id string, key []byte, err error) // Get the key used to encrypt new values.
+	Key(id string) (key []byte, err error)       // Get a key by ID to decrypt existing values.
+}
+
+// rekeyValue is a value re-encrypted by Rekey, with the value it replaces.
+type rekeyValue struct {
+	key  []byte
+	src  []byte
+	dest []byte
+}
+
+// StaticKeyring is a Keyring that holds keys in memory.
+type StaticKeyring struct {
+	current string
+	keys    map[string][]byte
+
+	mu sync.RWMutex
+}
+
+// AllowUnencrypted sets whether values without an encryption header are accepted by Unmarshal.
+// This should only be enabled while migrating existing data, as anyone who can write to storage could otherwise plant unencrypted values.
+// Use Rekey to encrypt existing values, then disable it again.
+func (m *EncryptedMarshaler[T]) AllowUnencrypted(allow bool) *EncryptedMarshaler[T] {
+	m.allowUnencrypted = allow
+	return m
+}
+
+func (m *EncryptedMarshaler[T]) Factory() T {
+	return m.m.Factory()
+}
+
+func (m *EncryptedMarshaler[T]) Marshal(src T) ([]byte, error) {
+	raw, err := m.m.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+	return m.seal(raw)
+}
+
+// Rekey re-encrypts every value in a LevelDB collection that was not encrypted with the current key, including unencrypted values regardless of AllowUnencrypted.
+// Documents are not unmarshaled, so this is considerably cheaper than rewriting every document.
+//
+// The collection must be open and must use this marshaler directly, not wrapped by any other marshaler, otherwise ErrMarshalerMismatch is returned.
+//
+// Rekey can run while the collection is in use. Values are re-encrypted in batches, and each batch is written while the collection is locked for writing.
+// A value that has been changed or deleted since it was read is not written, since any new value is already encrypted with the current key.
+func (m *EncryptedMarshaler[T]) Rekey(c *LevelDBCollection[T]) (int, error) {
+	if c.db == nil {
+		return 0, ErrClosed
+	}
+	if c.m != DocumentMarshaler[T, []byte](m) {
+		return 0, fmt.Errorf("%w: collection uses %T", ErrMarshalerMismatch, c.m)
+	}
+
+	current, _, err := m.keys.Current()
+	if err != nil {
+		return 0, err
+	}
+
+	iter := c.db.NewIterator(nil, c.optRead)
+	defer iter.Release()
+
+	n := 0
+	pending := []rekeyValue{}
+	for iter.Next() {
+		if !isDocumentKey(string(iter.Key())) {
+			continue
+		}
+
+		src := iter.Value()
+		if id, ok := encryptedKeyID(src); ok && id == current {
+			continue
+		}
+
+		raw, err := m.open(src)
+		if err != nil {
+			return n, &DocumentError{Key: string(iter.Key()), Err: err}
+		}
+		dest, err := m.seal(raw)
+		if err != nil {
+			return n, err
+		}
+
+		pending = append(pending, rekeyValue{key: bytes.Clone(iter.Key()), src: bytes.Clone(src), dest: dest})
+		if len(pending) >= rekeyBatchSize {
+			written, err := m.rekeyBatch(c, pending)
+			n += written
+			if err != nil {
+				return n, err
+			}
+			pending = pending[:0]
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return n, err
+	}
+
+	written, err := m.rekeyBatch(c, pending)
+	return n + written, err
+}
+
+func (m *EncryptedMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	if !m.allowUnencrypted && !bytes.HasPrefix(src, encryptedHeader) {
+		return ErrNotEncrypted
+	}
+
+	raw, err := m.open(src)
+	if err != nil {
+		return err
+	}
+	return m.m.Unmarshal(raw, dest)
+}
+
+// open decrypts a value.
+// Unencrypted values are returned as-is.
+func (m *EncryptedMarshaler[T]) open(src []byte) ([]byte, error) {
+	if !bytes.HasPrefix(src, encryptedHeader) {
+		return src, nil
+	}
+
+	id, ok := encryptedKeyID(src)
+	if !ok {
+		return nil, ErrDecrypt
+	}
+	key, err := m.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := len(encryptedHeader) + 2 + len(id)
+	if len(src) < prefix+aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce := src[prefix : prefix+aead.NonceSize()]
+
+	raw, err := aead.Open(nil, nonce, src[prefix+aead.NonceSize():], src[:prefix])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return raw, nil
+}
+
+// rekeyBatch writes re-encrypted values, skipping any that have changed since they were read.
+func (m *EncryptedMarshaler[T]) rekeyBatch(c *LevelDBCollection[T], values []rekeyValue) (int, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	batch := &leveldb.Batch{}
+	for _, v := range values {
+		stored, err := c.db.Get(v.key, c.optRead)
+		if errors.Is(err, leveldb.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if bytes.Equal(stored, v.src) {
+			batch.Put(v.key, v.dest)
+		}
+	}
+
+	if err := c.db.Write(batch, c.optWrite); err != nil {
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+// seal encrypts a value with the current key.
+func (m *EncryptedMarshaler[T]) seal(raw []byte) ([]byte, error) {
+	id, key, err := m.keys.Current()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, ErrInvalidKeyID
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := make([]byte, 0, len(encryptedHeader)+2+len(id)+aead.NonceSize()+len(raw)+aead.Overhead())
+	dest = append(dest, encryptedHeader...)
+	dest = append(dest, encryptedVersion, byte(len(id)))
+	dest = append(dest, id...)
+	prefix := len(dest)
+
+	nonce := dest[prefix : prefix+aead.NonceSize()]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	dest = dest[:prefix+aead.NonceSize()]
+
+	// The header and key ID are authenticated so they cannot be altered to select another key
+	return aead.Seal(dest, nonce, raw, dest[:prefix]), nil
+}
+
+func (k *StaticKeyring) Add(id string, key []byte) error {
+	if len(id) == 0 || len(id) > 255 {
+		return ErrInvalidKeyID
+	}
+	if _, err := aes.NewCipher(key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[id] = bytes.Clone(key)
+	return nil
+}
+
+func (k *StaticKeyring) Current() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[k.current]
+	if !ok {
+		return k.current, nil, ErrKeyNotFound
+	}
+	return k.current, key, nil
+}
+
+func (k *StaticKeyring) Key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// Use sets the key used to encrypt new values.
+// The key must already have been added to the keyring.
+func (k *StaticKeyring) Use(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.keys[id]; !ok {
+		return ErrKeyNotFound
+	}
+	k.current = id
+	return nil
+}
+
+// Encrypted creates a DocumentMarshaler that encrypts the output of another DocumentMarshaler m using AES-GCM.
+//
+// Each value stores the ID of the key used to encrypt it, so keys can be rotated by adding a new key to the keyring and using it as the current key.
+// Existing values are re-encrypted with the current key whenever they are next written, or all at once using Rekey.
+// Values without an encryption header are rejected with ErrNotEncrypted.
+// To read values written before encryption was enabled, use AllowUnencrypted until they have been re-encrypted by Rekey.
+//
+// If combining with Compressed, compress before encrypting, i.e. Encrypted(Compressed(m, codec), keys).
+func Encrypted[T any](m DocumentMarshaler[T, []byte], keys Keyring) *EncryptedMarshaler[T] {
+	return &EncryptedMarshaler[T]{
+		m:    m,
+		keys: keys,
+	}
+}
+
+// NewStaticKeyring creates a Keyring holding the given keys, using the key current to encrypt new values.
+func NewStaticKeyring(current string, keys map[string][]byte) (*StaticKeyring, error) {
+	k := &StaticKeyring{keys: map[string][]byte{}}
+	for id, key := range keys {
+		if err := k.Add(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := k.Use(current); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// encryptedKeyID reads the key ID from an encrypted value.
+func encryptedKeyID(src []byte) (string, bool) {
+	if !bytes.HasPrefix(src, encryptedHeader) || len(src) < len(encryptedHeader)+2 {
+		return "", false
+	}
+	if src[len(encryptedHeader)] != encryptedVersion {
+		return "", false
+	}
+
+	n := int(src[len(encryptedHeader)+1])
+	start := len(encryptedHeader) + 2
+	if len(src) < start+n {
+		return "", false
+	}
+	return string(src[start : start+n]), true
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
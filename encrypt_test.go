@@ -0,0 +1,203 @@
+package ezdb
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var (
+	testKey1 = bytes.Repeat([]byte{1}, 32)
+	testKey2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	keys, err := NewStaticKeyring("k1", map[string][]byte{"k1": testKey1})
+	if err != nil {
+		t.Fatalf("failed to create keyring (%q)", err)
+	}
+	m := Encrypted(studentMarshaler, keys)
+
+	for key, value := range students {
+		t.Logf("encrypting student '%s'", key)
+		b, err := m.Marshal(value)
+		if err != nil {
+			t.Errorf("failed to encrypt student '%s' (%q)", key, err)
+			continue
+		}
+		if bytes.Contains(b, []byte(value.Name)) {
+			t.Errorf("student '%s' was not encrypted", key)
+		}
+
+		actual := m.Factory()
+		if err := m.Unmarshal(b, actual); err != nil {
+			t.Errorf("failed to decrypt student '%s' (%q)", key, err)
+		} else if err := compareStudent(key, value, actual); err != nil {
+			t.Error(err)
+		}
+
+		// Tampering with the value must be detected
+		b[len(b)-1] ^= 0xff
+		if err := m.Unmarshal(b, m.Factory()); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("expected decrypt error for tampered student '%s', got %v", key, err)
+		}
+	}
+
+	// Unencrypted values are rejected unless allowed
+	for key, b := range studentsMarshaled {
+		if err := m.Unmarshal(b, m.Factory()); !errors.Is(err, ErrNotEncrypted) {
+			t.Errorf("expected unencrypted student '%s' to be rejected, got %v", key, err)
+		}
+	}
+
+	m.AllowUnencrypted(true)
+	for key, b := range studentsMarshaled {
+		actual := m.Factory()
+		if err := m.Unmarshal(b, actual); err != nil {
+			t.Errorf("failed to read unencrypted student '%s' (%q)", key, err)
+		}
+	}
+}
+
+func TestStaticKeyringCopiesKeys(t *testing.T) {
+	key := bytes.Clone(testKey1)
+	keys, err := NewStaticKeyring("k1", map[string][]byte{"k1": key})
+	if err != nil {
+		t.Fatalf("failed to create keyring (%q)", err)
+	}
+	m := Encrypted(studentMarshaler, keys)
+
+	b, err := m.Marshal(students["annie"])
+	if err != nil {
+		t.Fatalf("failed to encrypt student (%q)", err)
+	}
+
+	// Changing the caller's key must not affect the keyring
+	key[0] ^= 0xff
+	if err := m.Unmarshal(b, m.Factory()); err != nil {
+		t.Errorf("failed to decrypt student after changing caller's key (%q)", err)
+	}
+}
+
+func TestEncryptedRekey(t *testing.T) {
+	keys, err := NewStaticKeyring("k1", map[string][]byte{"k1": testKey1})
+	if err != nil {
+		t.Fatalf("failed to create keyring (%q)", err)
+	}
+	m := Encrypted(studentMarshaler, keys)
+
+	path := ".leveldb/encrypt_test"
+	c := LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	// Rotate to a new key
+	if err := keys.Add("k2", testKey2); err != nil {
+		t.Fatalf("failed to add key (%q)", err)
+	}
+	if err := keys.Use("k2"); err != nil {
+		t.Fatalf("failed to use key (%q)", err)
+	}
+
+	n, err := m.Rekey(c)
+	if err != nil {
+		t.Fatalf("failed to rekey collection (%q)", err)
+	} else if n != len(students) {
+		t.Errorf("incorrect number of students rekeyed (expected %d, got %d)", len(students), n)
+	}
+
+	// Nothing should remain to be rekeyed
+	if n, err := m.Rekey(c); err != nil {
+		t.Fatalf("failed to rekey collection again (%q)", err)
+	} else if n != 0 {
+		t.Errorf("expected no students to be rekeyed again, got %d", n)
+	}
+
+	// Students must be readable using only the new key
+	m.keys, _ = NewStaticKeyring("k2", map[string][]byte{"k2": testKey2})
+	for key, expected := range students {
+		actual, err := c.Get(key)
+		if err != nil {
+			t.Errorf("failed to get student '%s' after rekey (%q)", key, err)
+		} else if err := compareStudent(key, expected, actual); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEncryptedRekeyConcurrentPut(t *testing.T) {
+	keys, err := NewStaticKeyring("k1", map[string][]byte{"k1": testKey1})
+	if err != nil {
+		t.Fatalf("failed to create keyring (%q)", err)
+	}
+	m := Encrypted(studentMarshaler, keys)
+
+	path := ".leveldb/encrypt_concurrent_test"
+	c := LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	if err := c.Put("annie", students["annie"]); err != nil {
+		t.Fatalf("failed to put student (%q)", err)
+	}
+	src, err := c.db.Get([]byte("annie"), nil)
+	if err != nil {
+		t.Fatalf("failed to read student (%q)", err)
+	}
+	raw, err := m.open(src)
+	if err != nil {
+		t.Fatalf("failed to decrypt student (%q)", err)
+	}
+	dest, err := m.seal(raw)
+	if err != nil {
+		t.Fatalf("failed to encrypt student (%q)", err)
+	}
+
+	// A put between reading and writing a batch must not be overwritten with the older value
+	if err := c.Put("annie", &Student{Name: "Annie", Age: 33}); err != nil {
+		t.Fatalf("failed to update student (%q)", err)
+	}
+	if n, err := m.rekeyBatch(c, []rekeyValue{{key: []byte("annie"), src: src, dest: dest}}); err != nil {
+		t.Fatalf("failed to write batch (%q)", err)
+	} else if n != 0 {
+		t.Errorf("expected changed student not to be written, got %d", n)
+	}
+	if value, err := c.Get("annie"); err != nil || value.Age != 33 {
+		t.Errorf("expected updated student to be kept (got %v, %q)", value, err)
+	}
+}
+
+func TestEncryptedRekeyWrapped(t *testing.T) {
+	keys, err := NewStaticKeyring("k1", map[string][]byte{"k1": testKey1})
+	if err != nil {
+		t.Fatalf("failed to create keyring (%q)", err)
+	}
+	m := Encrypted(studentMarshaler, keys)
+
+	path := ".leveldb/encrypt_wrapped_test"
+	c := LevelDB[*Student](path, Checksummed[*Student](m), nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	if err := c.Put("annie", students["annie"]); err != nil {
+		t.Fatalf("failed to put student (%q)", err)
+	}
+	if _, err := m.Rekey(c); !errors.Is(err, ErrMarshalerMismatch) {
+		t.Errorf("expected ErrMarshalerMismatch rekeying a collection with a wrapped marshaler (got %q)", err)
+	}
+	if _, err := c.Get("annie"); err != nil {
+		t.Errorf("failed to get student after rejected rekey (%q)", err)
+	}
+}
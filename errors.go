@@ -1,12 +1,46 @@
 package ezdb
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 // High-level EZ DB error.
 // These are not exhaustive and your chosen implementation of Collection may produce its own errors.
 var (
-	ErrClosed     = errors.New("collection is closed")
-	ErrInvalidKey = errors.New("invalid key")
-	ErrNotFound   = errors.New("not found")
-	ErrReleased   = errors.New("iterator has been released")
+	ErrClosed          = errors.New("collection is closed")
+	ErrConstraint      = errors.New("constraint violated")
+	ErrInvalidDocument = errors.New("invalid document")
+	ErrInvalidKey      = errors.New("invalid key")
+	ErrNotFound        = errors.New("not found")
+	ErrReleased        = errors.New("iterator has been released")
+
+	ErrChecksum          = errors.New("checksum mismatch")
+	ErrDecrypt           = errors.New("failed to decrypt value")
+	ErrInvalidConstraint = errors.New("invalid constraint")
+	ErrInvalidKeyID      = errors.New("invalid encryption key ID")
+	ErrInvalidPatch      = errors.New("invalid patch")
+	ErrInvalidPath       = errors.New("invalid JSON path")
+	ErrInvalidQuery      = errors.New("invalid query")
+	ErrKeyNotFound       = errors.New("encryption key not found")
+	ErrMarshalerMismatch = errors.New("collection does not use this marshaler")
+	ErrNoUnusedKey       = errors.New("no unused key generated")
+	ErrNotEncrypted      = errors.New("value is not encrypted")
+	ErrNotJSON           = errors.New("documents are not stored as plain JSON")
+	ErrUnknownCodec      = errors.New("unknown compression codec")
+	ErrVersion           = errors.New("unsupported document version")
 )
+
+// DocumentError is an error relating to a specific document.
+type DocumentError struct {
+	Key string
+	Err error
+}
+
+func (e *DocumentError) Error() string {
+	return fmt.Sprintf("document '%s': %v", e.Key, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
@@ -1,7 +1,17 @@
 module github.com/annybs/ezdb
 
-go 1.21
+go 1.23
 
-require github.com/syndtr/goleveldb v1.0.0
+require (
+	github.com/fxamacker/cbor/v2 v2.7.0
+	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db
+	github.com/klauspost/compress v1.17.9
+	github.com/syndtr/goleveldb v1.0.0
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	google.golang.org/protobuf v1.34.2
+)
 
-require github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+)
@@ -0,0 +1,34 @@
+package ezdb
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// GobMarshaler is a DocumentMarshaler that converts documents to gob data.
+type GobMarshaler[T any] struct {
+	factory func() T
+}
+
+func (m *GobMarshaler[T]) Factory() T {
+	return m.factory()
+}
+
+func (m *GobMarshaler[T]) Marshal(src T) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(src); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *GobMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	return gob.NewDecoder(bytes.NewReader(src)).Decode(dest)
+}
+
+// Gob creates a DocumentMarshaler that converts documents to gob data.
+//
+// Each document is encoded independently, so type information is included in every value.
+func Gob[T any](factory func() T) *GobMarshaler[T] {
+	return &GobMarshaler[T]{factory: factory}
+}
@@ -0,0 +1,50 @@
+package ezdb
+
+// Change describes a document being put into or deleted from a collection.
+type Change[T any] struct {
+	Key    string
+	Old    T    // Previous value of the document, if it existed.
+	Exists bool // Whether the document existed before the change.
+	New    T    // New value of the document. This is the zero value when deleting.
+}
+
+// Hook is called when a document is put into or deleted from a collection.
+//
+// Hooks registered with BeforePut or BeforeDelete are called while the collection is locked for writing, before any checks or changes.
+// They can veto the change by returning an error, which is returned by Put or Delete, and a BeforePut hook can modify the new value of the document.
+// They must not write to the same collection.
+//
+// Hooks registered with AfterPut or AfterDelete are called once the change has been written and the collection is unlocked.
+// If an after hook returns an error, later hooks are not called and the error is returned, but the change is not undone.
+// The after hooks of a change are those registered when it was written, so hooks may be registered concurrently with writes.
+//
+// In a LevelDB collection, if the previous value of a document cannot be unmarshaled, Put and Delete return a DocumentError instead of calling hooks.
+type Hook[T any] func(c *Change[T]) error
+
+// hooks registered on a collection.
+type hooks[T any] struct {
+	afterDelete  []Hook[T]
+	afterPut     []Hook[T]
+	beforeDelete []Hook[T]
+	beforePut    []Hook[T]
+}
+
+// deletes returns true if there are any hooks for deletes.
+func (h *hooks[T]) deletes() bool {
+	return len(h.beforeDelete) > 0 || len(h.afterDelete) > 0
+}
+
+// puts returns true if there are any hooks for puts.
+func (h *hooks[T]) puts() bool {
+	return len(h.beforePut) > 0 || len(h.afterPut) > 0
+}
+
+// runHooks calls hooks in order of registration, stopping at the first error.
+func runHooks[T any](hs []Hook[T], c *Change[T]) error {
+	for _, h := range hs {
+		if err := h(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	errVeto := errors.New("vetoed")
+
+	path := ".leveldb/leveldb_hooks_test"
+	l := LevelDB[*Student](path, studentMarshaler, nil)
+	defer l.Destroy()
+
+	m := Memory[*Student](nil)
+
+	// Each collection has its own registration methods, so register hooks through a function
+	collections := map[string]struct {
+		c        Collection[*Student]
+		register func(beforePut, afterPut, beforeDelete, afterDelete Hook[*Student])
+	}{
+		"leveldb": {l, func(beforePut, afterPut, beforeDelete, afterDelete Hook[*Student]) {
+			l.BeforePut(beforePut).AfterPut(afterPut).BeforeDelete(beforeDelete).AfterDelete(afterDelete)
+		}},
+		"memory": {m, func(beforePut, afterPut, beforeDelete, afterDelete Hook[*Student]) {
+			m.BeforePut(beforePut).AfterPut(afterPut).BeforeDelete(beforeDelete).AfterDelete(afterDelete)
+		}},
+	}
+
+	for name, hc := range collections {
+		c := hc.c
+		if err := c.Open(); err != nil {
+			t.Fatalf("(%s) failed to open collection (%q)", name, err)
+		}
+
+		audit := []string{}
+		beforePut := func(ch *Change[*Student]) error {
+			if ch.New.Name == "Voldemort" {
+				return errVeto
+			}
+			if ch.New.Age == 0 {
+				ch.New = &Student{Name: ch.New.Name, Age: 18}
+			}
+			return nil
+		}
+		afterPut := func(ch *Change[*Student]) error {
+			if ch.Exists {
+				audit = append(audit, fmt.Sprintf("put %s: %s %d -> %s %d", ch.Key, ch.Old.Name, ch.Old.Age, ch.New.Name, ch.New.Age))
+			} else {
+				audit = append(audit, fmt.Sprintf("put %s: %s %d", ch.Key, ch.New.Name, ch.New.Age))
+			}
+			return nil
+		}
+		beforeDelete := func(ch *Change[*Student]) error {
+			if ch.Key == "annie" {
+				return errVeto
+			}
+			return nil
+		}
+		afterDelete := func(ch *Change[*Student]) error {
+			audit = append(audit, fmt.Sprintf("delete %s: %s (%v)", ch.Key, ch.Old.Name, ch.Exists))
+			// Writing to the same collection from an after hook must not deadlock
+			if ch.Key == "ben" {
+				return c.Delete("ben-copy")
+			}
+			return nil
+		}
+		hc.register(beforePut, afterPut, beforeDelete, afterDelete)
+
+		if err := c.Put("annie", &Student{Name: "Annie", Age: 32}); err != nil {
+			t.Errorf("(%s) failed to put student (%q)", name, err)
+		}
+		if err := c.Put("annie", &Student{Name: "Annie", Age: 33}); err != nil {
+			t.Errorf("(%s) failed to update student (%q)", name, err)
+		}
+		if err := c.Put("ben", &Student{Name: "Ben"}); err != nil {
+			t.Errorf("(%s) failed to put student (%q)", name, err)
+		}
+		if err := c.Put("ben-copy", &Student{Name: "Ben", Age: 50}); err != nil {
+			t.Errorf("(%s) failed to put student (%q)", name, err)
+		}
+		if err := c.Put("tom", &Student{Name: "Voldemort"}); !errors.Is(err, errVeto) {
+			t.Errorf("(%s) expected put to be vetoed (got %q)", name, err)
+		}
+		if err := c.Delete("annie"); !errors.Is(err, errVeto) {
+			t.Errorf("(%s) expected delete to be vetoed (got %q)", name, err)
+		}
+		if err := c.Delete("ben"); err != nil {
+			t.Errorf("(%s) failed to delete student (%q)", name, err)
+		}
+
+		expected := []string{
+			"put annie: Annie 32",
+			"put annie: Annie 32 -> Annie 33",
+			"put ben: Ben 18",
+			"put ben-copy: Ben 50",
+			"delete ben: Ben (true)",
+			"delete ben-copy: Ben (true)",
+		}
+		if fmt.Sprint(audit) != fmt.Sprint(expected) {
+			t.Errorf("(%s) incorrect audit trail (expected %q, got %q)", name, expected, audit)
+		}
+
+		if keys := c.Iter().GetAllKeys(); len(keys) != 1 || keys[0] != "annie" {
+			t.Errorf("(%s) incorrect students after hooks (expected [annie], got %v)", name, keys)
+		}
+		if has, _ := c.Has("tom"); has {
+			t.Errorf("(%s) expected vetoed student not to be stored", name)
+		}
+	}
+}
+
+func TestHooksConcurrentRegistration(t *testing.T) {
+	c := Memory[*Student](nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	// Hooks may be registered while other goroutines write
+	wg := sync.WaitGroup{}
+	for n := 0; n < 10; n++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.AfterPut(func(ch *Change[*Student]) error { return nil })
+		}()
+		go func(n int) {
+			defer wg.Done()
+			if err := c.Put(fmt.Sprintf("student%d", n), &Student{Name: "Student"}); err != nil {
+				t.Errorf("failed to put student %d (%q)", n, err)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func TestHooksUnreadableDocument(t *testing.T) {
+	path := ".leveldb/leveldb_hooks_unreadable_test"
+	raw := LevelDB[[]byte](path, Bytes(), nil)
+	if err := raw.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer raw.Destroy()
+
+	if err := raw.Put("annie", []byte("not a student")); err != nil {
+		t.Fatalf("failed to put invalid student (%q)", err)
+	}
+	if err := raw.Close(); err != nil {
+		t.Fatalf("failed to close collection (%q)", err)
+	}
+
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	c.BeforePut(func(ch *Change[*Student]) error {
+		return nil
+	})
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Close()
+
+	// The previous value cannot be read, so it must not be reported as missing
+	var docErr *DocumentError
+	if err := c.Put("annie", &Student{Name: "Annie"}); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected DocumentError putting over unreadable student (got %q)", err)
+	}
+}
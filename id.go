@@ -0,0 +1,267 @@
+package ezdb
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator generates keys for new documents.
+// Generators must be safe for concurrent use.
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// SequenceStore persists the state of a SequenceGenerator.
+type SequenceStore interface {
+	Load() (uint64, error) // Load the saved limit, or 0 if none has been saved.
+	Save(limit uint64) error
+}
+
+// SequenceGenerator generates keys from a monotonic counter, starting at 1.
+// Keys are formatted as 20-digit decimal numbers so that they sort in order.
+//
+// If the generator has a store, numbers are reserved in blocks and the limit of each block is saved before any number in it is used.
+// After a restart, the generator continues from the saved limit, so numbers are never reused although some may be skipped.
+type SequenceGenerator struct {
+	mu    sync.Mutex
+	store SequenceStore
+	block uint64
+
+	next  uint64
+	limit uint64
+}
+
+// SnowflakeGenerator generates k-sortable keys from a timestamp, node ID and counter.
+// Keys are formatted as 19-digit decimal numbers so that they sort in order.
+//
+// Each ID consists of 41 bits of milliseconds since SnowflakeEpoch, 10 bits of node ID and 12 bits of counter.
+// Generators with different node IDs never produce the same ID.
+type SnowflakeGenerator struct {
+	mu   sync.Mutex
+	node uint64
+	now  func() time.Time
+
+	ms  uint64
+	seq uint64
+}
+
+// ULIDGenerator generates ULIDs, which are 26-character, lexicographically sortable identifiers.
+// IDs generated within the same millisecond are monotonic.
+//
+// See https://github.com/ulid/spec
+type ULIDGenerator struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	ms      uint64
+	entropy [10]byte
+}
+
+// UUIDv7Generator generates version 7 UUIDs, which begin with a timestamp and so sort in order of creation.
+// IDs generated within the same millisecond are monotonic, using a 12-bit counter as described in RFC 9562.
+type UUIDv7Generator struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	ms  uint64
+	seq uint64
+}
+
+// SnowflakeEpoch is the start of time for Snowflake IDs.
+var SnowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// insertAttempts is the number of keys tried by Insert before giving up.
+const insertAttempts = 10
+
+// crockford is the Crockford base 32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g *SequenceGenerator) NewID() (string, error) {
+	n, err := g.Next()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%020d", n), nil
+}
+
+// Next gets the next number in the sequence.
+func (g *SequenceGenerator) Next() (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.next == 0 {
+		start := uint64(1)
+		if g.store != nil {
+			limit, err := g.store.Load()
+			if err != nil {
+				return 0, err
+			}
+			start = max(start, limit)
+		}
+		g.next = start
+		g.limit = start
+	}
+
+	if g.next >= g.limit {
+		limit := g.next + g.block
+		if g.store != nil {
+			if err := g.store.Save(limit); err != nil {
+				return 0, err
+			}
+		}
+		g.limit = limit
+	}
+
+	n := g.next
+	g.next++
+	return n, nil
+}
+
+func (g *SnowflakeGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().Sub(SnowflakeEpoch).Milliseconds())
+	if ms > g.ms {
+		g.ms = ms
+		g.seq = 0
+	} else {
+		// The clock has not moved forward, so continue from the last timestamp
+		g.seq++
+		if g.seq >= 1<<12 {
+			g.ms++
+			g.seq = 0
+		}
+	}
+
+	id := g.ms<<22 | g.node<<12 | g.seq
+	return fmt.Sprintf("%019d", id&(1<<63-1)), nil
+}
+
+func (g *ULIDGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.ms {
+		g.ms = ms
+		if _, err := rand.Read(g.entropy[:]); err != nil {
+			return "", err
+		}
+	} else if !incrementBytes(g.entropy[:]) {
+		// Entropy overflowed, so move to the next millisecond
+		g.ms++
+	}
+
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], g.ms<<16)
+	copy(id[6:], g.entropy[:])
+
+	// Encode 128 bits as 26 base 32 characters, with 2 bits of padding at the start
+	s := make([]byte, 26)
+	for n := range s {
+		v := 0
+		for b := n*5 - 2; b < n*5+3; b++ {
+			v <<= 1
+			if b >= 0 && id[b/8]&(0x80>>(b%8)) != 0 {
+				v |= 1
+			}
+		}
+		s[n] = crockford[v]
+	}
+	return string(s), nil
+}
+
+func (g *UUIDv7Generator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.ms {
+		g.ms = ms
+		// Start the counter in the lower half of its range, leaving room to increment it
+		g.seq = uint64(binary.BigEndian.Uint16(id[6:8])) & 0x7ff
+	} else {
+		g.seq++
+		if g.seq >= 1<<12 {
+			g.ms++
+			g.seq = 0
+		}
+	}
+
+	binary.BigEndian.PutUint64(id[:8], g.ms<<16|0x7000|g.seq)
+	id[8] = id[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(id[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// insert puts a document with a generated key.
+// If the key is already in use, another is generated, up to insertAttempts times, after which ErrNoUnusedKey is returned.
+func insert(g IDGenerator, has func(key string) (bool, error), put func(key string) error) (string, error) {
+	for n := 0; n < insertAttempts; n++ {
+		key, err := g.NewID()
+		if err != nil {
+			return "", err
+		}
+
+		exists, err := has(key)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return key, put(key)
+		}
+	}
+	return "", fmt.Errorf("%w after %d attempts", ErrNoUnusedKey, insertAttempts)
+}
+
+// incrementBytes increments a big-endian number, returning false if it overflows.
+func incrementBytes(b []byte) bool {
+	for n := len(b) - 1; n >= 0; n-- {
+		b[n]++
+		if b[n] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Sequence creates a generator for monotonic numeric keys.
+//
+// If store is nil, the sequence is not persisted and restarts at 1.
+// Otherwise, block numbers are reserved at a time, so the store is written at most once per block. If block is less than 1, the store is written for every number.
+func Sequence(store SequenceStore, block int) *SequenceGenerator {
+	return &SequenceGenerator{
+		store: store,
+		block: uint64(max(block, 1)),
+	}
+}
+
+// Snowflake creates a generator for Snowflake IDs.
+// Only the lowest 10 bits of node are used.
+func Snowflake(node int64) *SnowflakeGenerator {
+	return &SnowflakeGenerator{
+		node: uint64(node) & (1<<10 - 1),
+		now:  time.Now,
+	}
+}
+
+// ULID creates a generator for ULIDs.
+func ULID() *ULIDGenerator {
+	return &ULIDGenerator{now: time.Now}
+}
+
+// UUIDv7 creates a generator for version 7 UUIDs.
+func UUIDv7() *UUIDv7Generator {
+	return &UUIDv7Generator{now: time.Now}
+}
@@ -0,0 +1,144 @@
+package ezdb
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+)
+
+func testIDGenerator(t *testing.T, name string, g IDGenerator, pattern *regexp.Regexp) {
+	ids := []string{}
+	for n := 0; n < 5000; n++ {
+		id, err := g.NewID()
+		if err != nil {
+			t.Fatalf("(%s) failed to generate ID (%q)", name, err)
+		}
+		if !pattern.MatchString(id) {
+			t.Fatalf("(%s) incorrect format of ID %s", name, id)
+		}
+		ids = append(ids, id)
+	}
+
+	if !slices.IsSorted(ids) {
+		t.Errorf("(%s) expected IDs to be in order", name)
+	}
+	if len(slices.Compact(ids)) != len(ids) {
+		t.Errorf("(%s) expected IDs to be unique", name)
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	// Use a clock that often stands still or goes backward, to test monotonicity
+	tick := 0
+	now := func() time.Time {
+		tick++
+		return time.UnixMilli(1700000000000 + int64(tick/100%3)*int64(tick/300))
+	}
+
+	ulid := ULID()
+	ulid.now = now
+	testIDGenerator(t, "ulid", ulid, regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`))
+
+	uuid := UUIDv7()
+	uuid.now = now
+	testIDGenerator(t, "uuidv7", uuid, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+
+	snowflake := Snowflake(5)
+	snowflake.now = now
+	testIDGenerator(t, "snowflake", snowflake, regexp.MustCompile(`^[0-9]{19}$`))
+
+	testIDGenerator(t, "sequence", Sequence(nil, 0), regexp.MustCompile(`^[0-9]{20}$`))
+
+	// 2024-01-01 in milliseconds since the Unix epoch, encoded in Crockford base 32
+	ulid.now = func() time.Time { return SnowflakeEpoch }
+	ulid.ms = 0
+	if id, _ := ulid.NewID(); id[:10] != "01HK153X00" {
+		t.Errorf("incorrect ULID timestamp (expected 01HK153X00, got %s)", id[:10])
+	}
+}
+
+func TestSequence(t *testing.T) {
+	path := ".leveldb/leveldb_sequence_test"
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	c.SetIDGenerator(c.Sequence("students", 10))
+	for n := 0; n < 3; n++ {
+		if _, err := c.Insert(&Student{Name: "Student"}); err != nil {
+			t.Fatalf("failed to insert student (%q)", err)
+		}
+	}
+
+	if keys := c.Iter().GetAllKeys(); !slices.Equal(keys, []string{"00000000000000000001", "00000000000000000002", "00000000000000000003"}) {
+		t.Errorf("incorrect inserted students (got %v)", keys)
+	}
+
+	// Simulate a restart, which should skip the remainder of the reserved block
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close collection (%q)", err)
+	}
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to reopen collection (%q)", err)
+	}
+	c.SetIDGenerator(c.Sequence("students", 10))
+
+	if key, err := c.Insert(&Student{Name: "Student"}); err != nil {
+		t.Errorf("failed to insert student (%q)", err)
+	} else if key != "00000000000000000011" {
+		t.Errorf("incorrect key after restart (expected 00000000000000000011, got %s)", key)
+	}
+
+	if n := c.Iter().Count(); n != 4 {
+		t.Errorf("incorrect count of students (expected 4, got %d)", n)
+	}
+	key := metaPrefix + "seq:students"
+	if err := c.Put(key, &Student{}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey putting reserved key (got %q)", err)
+	}
+	if _, err := c.Get(key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey getting reserved key (got %q)", err)
+	}
+	if _, err := c.Has(key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey checking reserved key (got %q)", err)
+	}
+	if err := c.Delete(key); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey deleting reserved key (got %q)", err)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	c := Memory[*Student](nil).SetIDGenerator(&repeatGenerator{ids: []string{"a", "a", "b"}})
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	for _, expected := range []string{"a", "b"} {
+		if key, err := c.Insert(&Student{Name: "Student"}); err != nil {
+			t.Errorf("failed to insert student (%q)", err)
+		} else if key != expected {
+			t.Errorf("incorrect key (expected %s, got %s)", expected, key)
+		}
+	}
+
+	if _, err := c.Insert(&Student{Name: "Student"}); !errors.Is(err, ErrNoUnusedKey) {
+		t.Errorf("expected ErrNoUnusedKey when no unused key is generated (got %q)", err)
+	}
+}
+
+// repeatGenerator generates a fixed list of IDs, then repeats the last one.
+type repeatGenerator struct {
+	ids []string
+}
+
+func (g *repeatGenerator) NewID() (string, error) {
+	id := g.ids[0]
+	if len(g.ids) > 1 {
+		g.ids = g.ids[1:]
+	}
+	return id, nil
+}
@@ -1,5 +1,7 @@
 package ezdb
 
+import "iter"
+
 // Collection is a key-value store for documents of any type.
 type Collection[T any] interface {
 	Open() error  // Open the collection.
@@ -11,6 +13,11 @@ type Collection[T any] interface {
 	Put(key string, value T) error        // Put a document into the collection.
 
 	Iter() Iterator[T] // Get an iterator for this collection.
+
+	All() iter.Seq2[string, T]             // Iterate over all documents. This is best-effort: iteration stops silently at the first error, so use Results if errors matter.
+	Keys() iter.Seq[string]                // Iterate over all document keys. This is best-effort: iteration stops silently at the first error, so use Results if errors matter.
+	Results() iter.Seq2[string, Result[T]] // Iterate over all documents, including any errors reading them.
+	Values() iter.Seq[T]                   // Iterate over all document values. This is best-effort: iteration stops silently at the first error, so use Results if errors matter.
 }
 
 // DocumentMarshaler facilitates conversion between two types - a document and its storage representation, depending on the implementation of the Collection.
@@ -24,15 +31,23 @@ type DocumentMarshaler[T1 any, T2 any] interface {
 // This function returns true if the document passes all checks defined in the filter.
 type FilterFunc[T any] func(key string, value T) bool
 
+// KeyFilterFunc processes a document key as part of a filter operation.
+// This function returns true if the key passes all checks defined in the filter.
+type KeyFilterFunc func(key string) bool
+
 // Iterator provides functionality to explore a collection.
 //
 // Be mindful that the order of documents is not assured by any Collection implementation.
 // Use the Sort or SortKeys function before iterating over documents to ensure deterministic sort.
+//
+// Seek assumes that documents are ordered by key.
+// If they are not, it moves to the first document in iteration order that satisfies the key comparison.
 type Iterator[T any] interface {
-	First() bool // Move the iterator to the first document. Returns false if there is no first document.
-	Last() bool  // Move the iterator to the last document. Returns false if there is no last document.
-	Next() bool  // Move the iterator to the next document. Returns false if there is no next document.
-	Prev() bool  // Move the iterator to the previous document. Returns false if there is no previous document.
+	First() bool          // Move the iterator to the first document. Returns false if there is no first document.
+	Last() bool           // Move the iterator to the last document. Returns false if there is no last document.
+	Next() bool           // Move the iterator to the next document. Returns false if there is no next document.
+	Prev() bool           // Move the iterator to the previous document. Returns false if there is no previous document.
+	Seek(key string) bool // Move the iterator to the first document with a key greater than or equal to key, or less than or equal to key if reversed. Returns false if there is no such document.
 
 	Release() // Release the iterator and any associated resources, including those of previous iterators.
 
@@ -45,9 +60,20 @@ type Iterator[T any] interface {
 	GetAll() (map[string]T, error) // Get all documents as a key-value map.
 	GetAllKeys() []string          // Get all document keys.
 
+	All() iter.Seq2[string, T] // Iterate over all documents, then release the iterator. Iteration stops at the first document that cannot be read.
+	Keys() iter.Seq[string]    // Iterate over all document keys, then release the iterator.
+	Values() iter.Seq[T]       // Iterate over all document values, then release the iterator. Iteration stops at the first document that cannot be read.
+	Err() error                // Get the error that stopped the last iteration using All, Keys or Values, if any.
+
 	Filter(f FilterFunc[T]) Iterator[T]      // Create a new iterator with a subset of documents. The previous iterator will not be affected.
+	KeyFilter(f KeyFilterFunc) Iterator[T]   // Create a new iterator with a subset of documents, selected by key without reading values. The previous iterator will not be affected.
+	Limit(n int) Iterator[T]                 // Create a new iterator with at most n documents. The previous iterator will not be affected.
+	Prefix(prefix string) Iterator[T]        // Create a new iterator with documents whose key starts with prefix. The previous iterator will not be affected.
+	Reverse() Iterator[T]                    // Create a new iterator with documents in reverse order. The previous iterator will not be affected.
+	Skip(n int) Iterator[T]                  // Create a new iterator without the first n documents. The previous iterator will not be affected.
 	Sort(f SortFunc[T]) Iterator[T]          // Create a new iterator with sorted documents. The previous iterator will not be affected.
 	SortKeys(f SortFunc[string]) Iterator[T] // Create a new iterator with documents sorted by key. The previous iterator will not be affected.
+	TopK(n int, f SortFunc[T]) Iterator[T]   // Create a new iterator with the first n sorted documents, without sorting every document. The previous iterator will not be affected.
 }
 
 // SortFunc compares two documents as part of a sort operation.
@@ -0,0 +1,409 @@
+package ezdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a path to a value within a JSON document, such as $.address.city or $.tags[0].
+// Use ParseJSONPath to create a JSONPath.
+//
+// JSONPath creates filters for FilterJSON that read only the value at the path from each marshaled document, without unmarshaling it.
+// This is only suitable for collections using JSONMarshaler.
+type JSONPath struct {
+	path  string
+	steps []jsonPathStep
+}
+
+// jsonPathStep is either an object member name or, if index is not negative, an array index.
+type jsonPathStep struct {
+	name  string
+	index int
+}
+
+// jsonFilterer is implemented by iterators that can filter documents by their marshaled JSON.
+type jsonFilterer[T any] interface {
+	filterJSON(f RawFilterFunc) (Iterator[T], error)
+}
+
+// jsonScanner reads through JSON without decoding it, so that a path can be found without allocating.
+type jsonScanner struct {
+	data []byte
+	pos  int
+}
+
+// FilterJSON creates a new iterator with a subset of documents, selected by their marshaled JSON using filters such as those created by JSONPath.
+// Documents that do not match are never unmarshaled. The previous iterator will not be affected.
+//
+// The iterator must read marshaled documents from a collection using JSONMarshaler, such as a LevelDB iterator that has not been sorted or filtered by value.
+// Otherwise, ErrNotJSON is returned with an iterator that has no documents.
+func FilterJSON[T any](i Iterator[T], f RawFilterFunc) (Iterator[T], error) {
+	if j, ok := i.(jsonFilterer[T]); ok {
+		return j.filterJSON(f)
+	}
+	return newMemoryIterator(map[string]T{}, []string{}, i), fmt.Errorf("%w: %T does not read marshaled documents", ErrNotJSON, i)
+}
+
+// ParseJSONPath parses a JSON path.
+// A path starts with $, followed by any number of .name, ["name"] or [index] steps.
+func ParseJSONPath(path string) (*JSONPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%w: %s must start with $", ErrInvalidPath, path)
+	}
+
+	steps := []jsonPathStep{}
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("%w: %s has an empty name", ErrInvalidPath, path)
+			}
+			steps = append(steps, jsonPathStep{name: name, index: -1})
+			rest = rest[end+1:]
+
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s has an unterminated [", ErrInvalidPath, path)
+			}
+			inner := rest[1:end]
+			if strings.HasPrefix(inner, `"`) {
+				name, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s has an invalid name %s", ErrInvalidPath, path, inner)
+				}
+				steps = append(steps, jsonPathStep{name: name, index: -1})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("%w: %s has an invalid index %s", ErrInvalidPath, path, inner)
+				}
+				steps = append(steps, jsonPathStep{index: index})
+			}
+			rest = rest[end+1:]
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q in %s", ErrInvalidPath, rest[0], path)
+		}
+	}
+
+	return &JSONPath{path: path, steps: steps}, nil
+}
+
+// MustParseJSONPath is like ParseJSONPath but panics if the path is invalid.
+func MustParseJSONPath(path string) *JSONPath {
+	p, err := ParseJSONPath(path)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Eq creates a filter that matches documents where the value at the path is equal to v.
+func (p *JSONPath) Eq(v any) RawFilterFunc {
+	want := jsonNormalize(v)
+	return p.Match(func(value any) bool {
+		return reflect.DeepEqual(value, want)
+	})
+}
+
+// Exists creates a filter that matches documents where the path exists, even if its value is null.
+func (p *JSONPath) Exists() RawFilterFunc {
+	return p.Match(func(value any) bool {
+		return true
+	})
+}
+
+// Gt creates a filter that matches documents where the value at the path is greater than v.
+// Numbers and strings can be compared.
+func (p *JSONPath) Gt(v any) RawFilterFunc {
+	return p.compare(v, func(n int) bool { return n > 0 })
+}
+
+// Gte creates a filter that matches documents where the value at the path is greater than or equal to v.
+// Numbers and strings can be compared.
+func (p *JSONPath) Gte(v any) RawFilterFunc {
+	return p.compare(v, func(n int) bool { return n >= 0 })
+}
+
+// Lookup gets the value at the path in a JSON document.
+// Objects, arrays and numbers are decoded as by json.Unmarshal into an empty interface.
+// Returns false if the path does not exist or the document is not valid JSON.
+//
+// Only the value at the path is decoded. The rest of the document is scanned without allocating, and is not fully validated.
+func (p *JSONPath) Lookup(data []byte) (any, bool) {
+	raw, ok := p.find(data)
+	if !ok {
+		return nil, false
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Lt creates a filter that matches documents where the value at the path is less than v.
+// Numbers and strings can be compared.
+func (p *JSONPath) Lt(v any) RawFilterFunc {
+	return p.compare(v, func(n int) bool { return n < 0 })
+}
+
+// Lte creates a filter that matches documents where the value at the path is less than or equal to v.
+// Numbers and strings can be compared.
+func (p *JSONPath) Lte(v any) RawFilterFunc {
+	return p.compare(v, func(n int) bool { return n <= 0 })
+}
+
+// Match creates a filter that matches documents where the path exists and f returns true for its value.
+// The value is decoded as by json.Unmarshal into an empty interface.
+func (p *JSONPath) Match(f func(value any) bool) RawFilterFunc {
+	return func(key string, data []byte) bool {
+		value, ok := p.Lookup(data)
+		return ok && f(value)
+	}
+}
+
+// Ne creates a filter that matches documents where the path exists and its value is not equal to v.
+func (p *JSONPath) Ne(v any) RawFilterFunc {
+	want := jsonNormalize(v)
+	return p.Match(func(value any) bool {
+		return !reflect.DeepEqual(value, want)
+	})
+}
+
+func (p *JSONPath) String() string {
+	return p.path
+}
+
+// compare creates a filter that compares the value at the path with v.
+// Numbers and strings without escapes are compared without decoding the value into an empty interface.
+func (p *JSONPath) compare(v any, f func(n int) bool) RawFilterFunc {
+	want := jsonNormalize(v)
+	wantNumber, isNumber := want.(float64)
+	wantString, isString := want.(string)
+	wantBytes := []byte(wantString)
+
+	return func(key string, data []byte) bool {
+		raw, ok := p.find(data)
+		if !ok || len(raw) == 0 {
+			return false
+		}
+
+		switch {
+		case isNumber && (raw[0] == '-' || (raw[0] >= '0' && raw[0] <= '9')):
+			a, err := strconv.ParseFloat(string(raw), 64)
+			if err != nil {
+				return false
+			}
+			switch {
+			case a < wantNumber:
+				return f(-1)
+			case a > wantNumber:
+				return f(1)
+			}
+			return f(0)
+
+		case isString && raw[0] == '"':
+			inner := raw[1 : len(raw)-1]
+			if bytes.IndexByte(inner, '\\') < 0 {
+				return f(bytes.Compare(inner, wantBytes))
+			}
+			var a string
+			if err := json.Unmarshal(raw, &a); err != nil {
+				return false
+			}
+			return f(strings.Compare(a, wantString))
+		}
+		return false
+	}
+}
+
+// find gets the marshaled value at the path in a JSON document.
+func (p *JSONPath) find(data []byte) ([]byte, bool) {
+	s := jsonScanner{data: data}
+
+	for _, step := range p.steps {
+		if step.index >= 0 {
+			if !s.consume('[') {
+				return nil, false
+			}
+			for n := 0; n < step.index; n++ {
+				if s.peek() == ']' || !s.skip() || !s.consume(',') {
+					return nil, false
+				}
+			}
+			if s.peek() == ']' {
+				return nil, false
+			}
+			continue
+		}
+
+		if !s.consume('{') {
+			return nil, false
+		}
+		found := false
+		for s.peek() != '}' {
+			match, ok := s.member(step.name)
+			if !ok {
+				return nil, false
+			}
+			if match {
+				found = true
+				break
+			}
+			if !s.skip() {
+				return nil, false
+			}
+			if !s.consume(',') {
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	s.peek()
+	start := s.pos
+	if !s.skip() {
+		return nil, false
+	}
+	return s.data[start:s.pos], true
+}
+
+// consume skips whitespace and the byte c, returning false if the next byte is not c.
+func (s *jsonScanner) consume(c byte) bool {
+	if s.peek() != c {
+		return false
+	}
+	s.pos++
+	return true
+}
+
+// member reads an object member name and the following colon, returning whether the name is equal to name.
+// Names without escapes are compared without allocating.
+func (s *jsonScanner) member(name string) (bool, bool) {
+	s.peek()
+	start := s.pos
+	raw, escaped, ok := s.str()
+	if !ok {
+		return false, false
+	}
+
+	match := string(raw) == name
+	if escaped {
+		var decoded string
+		if err := json.Unmarshal(s.data[start:s.pos], &decoded); err != nil {
+			return false, false
+		}
+		match = decoded == name
+	}
+	return match, s.consume(':')
+}
+
+// peek skips whitespace and gets the next byte, or 0 at the end of the data.
+func (s *jsonScanner) peek() byte {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return s.data[s.pos]
+		}
+	}
+	return 0
+}
+
+// skip skips the next value.
+func (s *jsonScanner) skip() bool {
+	switch s.peek() {
+	case 0:
+		return false
+
+	case '"':
+		_, _, ok := s.str()
+		return ok
+
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case '"':
+				if _, _, ok := s.str(); !ok {
+					return false
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					s.pos++
+					return true
+				}
+			}
+			s.pos++
+		}
+		return false
+
+	default:
+		// Numbers, true, false and null continue until a delimiter
+		start := s.pos
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case ',', '}', ']', ' ', '\t', '\n', '\r':
+				return s.pos > start
+			}
+			s.pos++
+		}
+		return s.pos > start
+	}
+}
+
+// str reads a string, returning its contents without quotes and whether it contains escapes.
+func (s *jsonScanner) str() ([]byte, bool, bool) {
+	if !s.consume('"') {
+		return nil, false, false
+	}
+
+	start := s.pos
+	escaped := false
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			escaped = true
+			s.pos += 2
+		case '"':
+			s.pos++
+			return s.data[start : s.pos-1], escaped, true
+		default:
+			s.pos++
+		}
+	}
+	return nil, false, false
+}
+
+// jsonNormalize converts a value to the form produced by json.Unmarshal into an empty interface, so that it can be compared with decoded values.
+func jsonNormalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return v
+	}
+	return value
+}
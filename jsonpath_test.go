@@ -0,0 +1,177 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	doc := []byte(`{"name":"Annie","tags":[{"x":1},"b",{"y":[2,3]}],"address":{"city":"London","zip":null},"say\"hi":"x"}`)
+
+	lookups := map[string]any{
+		`$.name`:              "Annie",
+		`$.tags[1]`:           "b",
+		`$.tags[2].y[1]`:      float64(3),
+		`$["address"].city`:   "London",
+		`$.address["zip"]`:    nil,
+		`$.tags[0]`:           map[string]any{"x": float64(1)},
+		`$.address.city.name`: false,
+		`$.tags[3]`:           false,
+		`$.age`:               false,
+		`$["say\"hi"]`:        "x",
+	}
+
+	for path, expected := range lookups {
+		value, ok := MustParseJSONPath(path).Lookup(doc)
+		if expected == false {
+			if ok {
+				t.Errorf("expected %s not to exist (got %v)", path, value)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("expected %s to exist", path)
+		} else if !reflect.DeepEqual(value, expected) {
+			t.Errorf("incorrect value at %s (expected %v, got %v)", path, expected, value)
+		}
+	}
+
+	for _, path := range []string{``, `name`, `$.`, `$[0`, `$[-1]`, `$["a]`, `$a`} {
+		if _, err := ParseJSONPath(path); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("expected ErrInvalidPath for %s (got %q)", path, err)
+		}
+	}
+}
+
+func TestJSONPathLevelDB(t *testing.T) {
+	m := &countingMarshaler[*Student]{DocumentMarshaler: studentMarshaler}
+
+	path := ".leveldb/leveldb_jsonpath_test"
+	c := LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	age := MustParseJSONPath("$.age")
+	iter := c.Iter().(*LevelDBIterator[*Student]).RawFilter(age.Gt(30))
+	defer iter.Release()
+
+	if n := iter.Count(); n != 2 {
+		t.Errorf("incorrect count of students (expected 2, got %d)", n)
+	}
+	if m.n != 0 {
+		t.Errorf("expected no students to be unmarshaled, got %d", m.n)
+	}
+
+	values, err := iter.(*LevelDBIterator[*Student]).RawFilter(MustParseJSONPath("$.name").Ne("Ben")).GetAll()
+	if err != nil {
+		t.Errorf("failed to get students (%q)", err)
+	} else if len(values) != 1 || values["annie"] == nil {
+		t.Errorf("incorrect students (expected annie, got %v)", values)
+	}
+	if m.n != 1 {
+		t.Errorf("expected 1 student to be unmarshaled, got %d", m.n)
+	}
+
+	window := c.Iter().Skip(1).(*LevelDBIterator[*Student]).RawFilter(age.Lte(32))
+	defer window.Release()
+	if keys := window.GetAllKeys(); len(keys) != 1 || keys[0] != "clive" {
+		t.Errorf("incorrect windowed students (expected [clive], got %v)", keys)
+	}
+}
+
+func TestFilterJSON(t *testing.T) {
+	path := ".leveldb/leveldb_filter_json_test"
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	over30 := MustParseJSONPath("$.age").Gt(30)
+
+	iter, err := FilterJSON(c.Iter().Prefix("a"), over30)
+	if err != nil {
+		t.Errorf("failed to filter students (%q)", err)
+	} else if keys := iter.GetAllKeys(); len(keys) != 1 || keys[0] != "annie" {
+		t.Errorf("incorrect students (expected [annie], got %v)", keys)
+	}
+	iter.Release()
+
+	// Documents that are no longer marshaled cannot be filtered
+	sorted := c.Iter().SortKeys(func(a, b string) bool { return a < b })
+	iter, err = FilterJSON(sorted, over30)
+	if !errors.Is(err, ErrNotJSON) {
+		t.Errorf("expected ErrNotJSON for sorted students, got %v", err)
+	} else if n := iter.Count(); n != 0 {
+		t.Errorf("expected no students after error, got %d", n)
+	}
+	iter.Release()
+
+	checksummed := LevelDB[*Student](".leveldb/leveldb_filter_json_checksum_test", Checksummed(studentMarshaler), nil)
+	if err := checksummed.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer checksummed.Destroy()
+
+	iter, err = FilterJSON(checksummed.Iter(), over30)
+	if !errors.Is(err, ErrNotJSON) {
+		t.Errorf("expected ErrNotJSON for checksummed students, got %v", err)
+	}
+	iter.Release()
+}
+
+func BenchmarkFilterJSON(b *testing.B) {
+	path := ".leveldb/leveldb_filter_json_bench"
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		b.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for n := 0; n < 1000; n++ {
+		key := fmt.Sprintf("student-%03d", n)
+		if err := c.Put(key, &Student{Name: key, Age: n % 100}); err != nil {
+			b.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	b.Run("Filter", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			iter := c.Iter().Filter(func(key string, value *Student) bool {
+				return value.Age > 90
+			})
+			iter.Count()
+			iter.Release()
+		}
+	})
+
+	b.Run("FilterJSON", func(b *testing.B) {
+		over90 := MustParseJSONPath("$.age").Gt(90)
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			iter, err := FilterJSON(c.Iter(), over90)
+			if err != nil {
+				b.Fatal(err)
+			}
+			iter.Count()
+			iter.Release()
+		}
+	})
+}
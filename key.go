@@ -1,10 +1,101 @@
 package ezdb
 
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Maximum length of a key quoted in an error message, in bytes.
+const maxQuotedKeyLength = 64
+
+// KeyPolicy restricts the keys that can be used to put data into a collection.
+// Empty keys are always invalid. Other restrictions apply only if they are set.
+type KeyPolicy struct {
+	// MaxLength is the maximum length of a key in bytes.
+	MaxLength int
+	// Pattern must match the whole key.
+	Pattern *regexp.Regexp
+	// ReservedPrefixes cannot be used at the start of a key.
+	ReservedPrefixes []string
+	// UTF8 requires keys to be valid UTF-8.
+	UTF8 bool
+	// NoControl forbids control characters, such as newlines, in keys.
+	NoControl bool
+
+	// Validate is called after other restrictions have been checked.
+	// Its errors are returned as they are, so they should wrap ErrInvalidKey.
+	Validate func(key string) error
+}
+
+// Check whether a key is valid for putting data into a collection.
+// If the policy is nil, only ValidateKey is applied.
+//
+// Keys are only checked when data is put into a collection, so keys already in the collection are not affected by a change of policy.
+func (p *KeyPolicy) Check(key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if p == nil {
+		return nil
+	}
+
+	if p.MaxLength > 0 && len(key) > p.MaxLength {
+		return fmt.Errorf("%w: key is %d bytes, maximum is %d", ErrInvalidKey, len(key), p.MaxLength)
+	}
+	if p.UTF8 && !utf8.ValidString(key) {
+		return fmt.Errorf("%w: key %s is not valid UTF-8", ErrInvalidKey, quoteKey(key))
+	}
+	if p.NoControl {
+		if n := strings.IndexFunc(key, unicode.IsControl); n >= 0 {
+			return fmt.Errorf("%w: key %s contains a control character at position %d", ErrInvalidKey, quoteKey(key), n)
+		}
+	}
+	for _, prefix := range p.ReservedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return fmt.Errorf("%w: key %s has reserved prefix %q", ErrInvalidKey, quoteKey(key), prefix)
+		}
+	}
+	if p.Pattern != nil {
+		if loc := p.Pattern.FindStringIndex(key); loc == nil || loc[0] != 0 || loc[1] != len(key) {
+			return fmt.Errorf("%w: key %s does not match %s", ErrInvalidKey, quoteKey(key), p.Pattern)
+		}
+	}
+	if p.Validate != nil {
+		return p.Validate(key)
+	}
+
+	return nil
+}
+
 // ValidateKey validates whether a key is valid for putting data into a collection.
 func ValidateKey(key string) error {
 	if key == "" {
 		return ErrInvalidKey
 	}
+	return nil
+}
 
+// checkDocumentKey returns an error if a key is reserved for internal use.
+func checkDocumentKey(key string) error {
+	if !isDocumentKey(key) {
+		return fmt.Errorf("%w: key %s is reserved for internal use", ErrInvalidKey, quoteKey(key))
+	}
 	return nil
 }
+
+// quoteKey quotes a key for an error message, truncating it if it is too long.
+func quoteKey(key string) string {
+	if len(key) <= maxQuotedKeyLength {
+		return strconv.Quote(key)
+	}
+
+	n := maxQuotedKeyLength
+	for n > 0 && !utf8.RuneStart(key[n]) {
+		n--
+	}
+	return fmt.Sprintf("%q... (%d bytes)", key[:n], len(key))
+}
@@ -0,0 +1,100 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestKeyPolicy(t *testing.T) {
+	errBanned := fmt.Errorf("%w: banned", ErrInvalidKey)
+
+	p := &KeyPolicy{
+		MaxLength:        16,
+		Pattern:          regexp.MustCompile(`[a-z0-9:\n\xff]+`),
+		ReservedPrefixes: []string{"sys:"},
+		UTF8:             true,
+		NoControl:        true,
+		Validate: func(key string) error {
+			if key == "banned" {
+				return errBanned
+			}
+			return nil
+		},
+	}
+
+	valid := []string{"annie", "student:42"}
+	for _, key := range valid {
+		if err := p.Check(key); err != nil {
+			t.Errorf("expected key %q to be valid (%q)", key, err)
+		}
+	}
+
+	invalid := []string{"", strings.Repeat("a", 17), "a\nb", "a\xffb", "sys:config", "Annie", "a b"}
+	for _, key := range invalid {
+		if err := p.Check(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey for key %q (got %q)", key, err)
+		}
+	}
+	if err := p.Check("banned"); err != errBanned {
+		t.Errorf("expected custom error for banned key (got %q)", err)
+	}
+
+	// Long keys are truncated in errors
+	long := strings.Repeat("é", 100) + "\n"
+	if err := (&KeyPolicy{NoControl: true}).Check(long); err == nil || !strings.Contains(err.Error(), "(201 bytes)") || strings.Contains(err.Error(), long) {
+		t.Errorf("expected error for long key to be truncated (got %q)", err)
+	}
+
+	var none *KeyPolicy
+	if err := none.Check("a\nb"); err != nil {
+		t.Errorf("expected nil policy to accept any non-empty key (got %q)", err)
+	}
+	if err := none.Check(""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected nil policy to reject empty key (got %q)", err)
+	}
+}
+
+func TestKeyPolicyCollection(t *testing.T) {
+	p := &KeyPolicy{MaxLength: 8, NoControl: true}
+
+	path := ".leveldb/leveldb_key_policy_test"
+	l := LevelDB[*Student](path, studentMarshaler, nil).SetKeyPolicy(p)
+	defer l.Destroy()
+
+	collections := map[string]Collection[*Student]{
+		"leveldb": l,
+		"memory":  Memory[*Student](nil).SetKeyPolicy(p),
+	}
+
+	for name, c := range collections {
+		if err := c.Open(); err != nil {
+			t.Fatalf("(%s) failed to open collection (%q)", name, err)
+		}
+
+		if err := c.Put("annie", students["annie"]); err != nil {
+			t.Errorf("(%s) failed to put student (%q)", name, err)
+		}
+		for _, key := range []string{"annie\n", "annabelle"} {
+			if err := c.Put(key, students["annie"]); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("(%s) expected ErrInvalidKey for key %q (got %q)", name, key, err)
+			}
+		}
+	}
+
+	// Only LevelDB reserves keys for internal use
+	reserved := metaPrefix + "a"
+	if err := ValidateKey(reserved); err != nil {
+		t.Errorf("expected ValidateKey to accept key %q (got %q)", reserved, err)
+	}
+	m := Memory[*Student](nil)
+	m.Open()
+	if err := m.Put(reserved, students["annie"]); err != nil {
+		t.Errorf("(memory) failed to put student with key %q (%q)", reserved, err)
+	}
+	if err := l.SetKeyPolicy(nil).Put(reserved, students["annie"]); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("(leveldb) expected ErrInvalidKey for key %q (got %q)", reserved, err)
+	}
+}
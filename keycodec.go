@@ -0,0 +1,110 @@
+package ezdb
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// KeyCodec encodes typed keys as strings for storage in a collection.
+// For keys to be iterated in order, the encoding must preserve it when compared bytewise, as LevelDB does.
+type KeyCodec[K any] interface {
+	Decode(key string) (K, error) // Decode a stored key.
+	Encode(key K) (string, error) // Encode a key for storage.
+}
+
+// Signed is a constraint for signed integer types.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is a constraint for unsigned integer types.
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// IntKeyCodec encodes signed integers as 8 big-endian bytes with the sign bit flipped, so that negative numbers sort before positive numbers.
+type IntKeyCodec[K Signed] struct{}
+
+// StringKeyCodec stores string keys as they are.
+type StringKeyCodec[K ~string] struct{}
+
+// UintKeyCodec encodes unsigned integers as 8 big-endian bytes.
+type UintKeyCodec[K Unsigned] struct{}
+
+// UUIDKeyCodec encodes UUIDs, or any other 16-byte array, as their 16 bytes.
+// This is compatible with common UUID types, such as github.com/google/uuid.UUID.
+type UUIDKeyCodec[K ~[16]byte] struct{}
+
+func (c *IntKeyCodec[K]) Decode(key string) (K, error) {
+	if len(key) != 8 {
+		return 0, fmt.Errorf("%w: expected 8 bytes, got %d", ErrInvalidKey, len(key))
+	}
+
+	n := int64(binary.BigEndian.Uint64([]byte(key)) ^ 1<<63)
+	if int64(K(n)) != n {
+		return 0, fmt.Errorf("%w: %d is out of range", ErrInvalidKey, n)
+	}
+	return K(n), nil
+}
+
+func (c *IntKeyCodec[K]) Encode(key K) (string, error) {
+	return string(binary.BigEndian.AppendUint64(nil, uint64(key)^1<<63)), nil
+}
+
+func (c *StringKeyCodec[K]) Decode(key string) (K, error) {
+	return K(key), nil
+}
+
+func (c *StringKeyCodec[K]) Encode(key K) (string, error) {
+	return string(key), nil
+}
+
+func (c *UintKeyCodec[K]) Decode(key string) (K, error) {
+	if len(key) != 8 {
+		return 0, fmt.Errorf("%w: expected 8 bytes, got %d", ErrInvalidKey, len(key))
+	}
+
+	n := binary.BigEndian.Uint64([]byte(key))
+	if uint64(K(n)) != n {
+		return 0, fmt.Errorf("%w: %d is out of range", ErrInvalidKey, n)
+	}
+	return K(n), nil
+}
+
+func (c *UintKeyCodec[K]) Encode(key K) (string, error) {
+	return string(binary.BigEndian.AppendUint64(nil, uint64(key))), nil
+}
+
+func (c *UUIDKeyCodec[K]) Decode(key string) (K, error) {
+	var id K
+	if len(key) != len(id) {
+		return id, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidKey, len(id), len(key))
+	}
+
+	copy(id[:], key)
+	return id, nil
+}
+
+func (c *UUIDKeyCodec[K]) Encode(key K) (string, error) {
+	return string(key[:]), nil
+}
+
+// IntKey creates a key codec for signed integers.
+func IntKey[K Signed]() *IntKeyCodec[K] {
+	return &IntKeyCodec[K]{}
+}
+
+// StringKey creates a key codec for strings.
+func StringKey[K ~string]() *StringKeyCodec[K] {
+	return &StringKeyCodec[K]{}
+}
+
+// UintKey creates a key codec for unsigned integers.
+func UintKey[K Unsigned]() *UintKeyCodec[K] {
+	return &UintKeyCodec[K]{}
+}
+
+// UUIDKey creates a key codec for UUIDs.
+func UUIDKey[K ~[16]byte]() *UUIDKeyCodec[K] {
+	return &UUIDKeyCodec[K]{}
+}
@@ -0,0 +1,141 @@
+package ezdb
+
+import "iter"
+
+// KeyedCollection wraps a collection to use keys of type K, which are encoded by a KeyCodec.
+//
+// Only the methods of the collection itself use typed keys.
+// Iterators, and the filters and sort functions applied to them, are provided by the underlying collection, so their keys are encoded strings.
+// Use Key to decode the current key of an iterator.
+//
+// The binary codecs IntKey, UintKey, UUIDKey and TupleKey produce keys that are not valid UTF-8 and contain control characters,
+// so they cannot be used with an underlying collection whose KeyPolicy sets UTF8 or NoControl.
+type KeyedCollection[K, T any] struct {
+	c     Collection[T]
+	codec KeyCodec[K]
+}
+
+// All iterates over documents whose keys can be decoded.
+// Documents whose keys cannot be decoded are skipped. Use Results to receive their errors.
+func (c *KeyedCollection[K, T]) All() iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		for key, value := range c.c.All() {
+			k, err := c.codec.Decode(key)
+			if err != nil {
+				continue
+			}
+			if !yield(k, value) {
+				return
+			}
+		}
+	}
+}
+
+func (c *KeyedCollection[K, T]) Close() error {
+	return c.c.Close()
+}
+
+// Collection gets the underlying collection.
+func (c *KeyedCollection[K, T]) Collection() Collection[T] {
+	return c.c
+}
+
+func (c *KeyedCollection[K, T]) Delete(key K) error {
+	k, err := c.codec.Encode(key)
+	if err != nil {
+		return err
+	}
+	return c.c.Delete(k)
+}
+
+func (c *KeyedCollection[K, T]) Get(key K) (T, error) {
+	k, err := c.codec.Encode(key)
+	if err != nil {
+		var value T
+		return value, err
+	}
+	return c.c.Get(k)
+}
+
+func (c *KeyedCollection[K, T]) Has(key K) (bool, error) {
+	k, err := c.codec.Encode(key)
+	if err != nil {
+		return false, err
+	}
+	return c.c.Has(k)
+}
+
+func (c *KeyedCollection[K, T]) Iter() Iterator[T] {
+	return c.c.Iter()
+}
+
+// Key decodes the current key of an iterator created by this collection.
+func (c *KeyedCollection[K, T]) Key(i Iterator[T]) (K, error) {
+	return c.codec.Decode(i.Key())
+}
+
+// Keys iterates over keys that can be decoded.
+// Keys that cannot be decoded are skipped. Use Results to receive their errors.
+func (c *KeyedCollection[K, T]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range c.c.Keys() {
+			k, err := c.codec.Decode(key)
+			if err != nil {
+				continue
+			}
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func (c *KeyedCollection[K, T]) Open() error {
+	return c.c.Open()
+}
+
+// Prefix creates an iterator over documents whose encoded keys start with the encoded prefix.
+// For example, with TupleKey, this selects documents with keys that start with the elements of the prefix tuple.
+func (c *KeyedCollection[K, T]) Prefix(prefix K) (Iterator[T], error) {
+	k, err := c.codec.Encode(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return c.c.Iter().Prefix(k), nil
+}
+
+func (c *KeyedCollection[K, T]) Put(key K, value T) error {
+	k, err := c.codec.Encode(key)
+	if err != nil {
+		return err
+	}
+	return c.c.Put(k, value)
+}
+
+// Results iterates over all documents.
+// If a key cannot be decoded, its result has a DocumentError with the encoded key, and the zero value of K is yielded in its place.
+func (c *KeyedCollection[K, T]) Results() iter.Seq2[K, Result[T]] {
+	return func(yield func(K, Result[T]) bool) {
+		for key, result := range c.c.Results() {
+			k, err := c.codec.Decode(key)
+			if err != nil && result.Err == nil {
+				result.Err = &DocumentError{Key: key, Err: err}
+			}
+			if !yield(k, result) {
+				return
+			}
+		}
+	}
+}
+
+func (c *KeyedCollection[K, T]) Values() iter.Seq[T] {
+	return c.c.Values()
+}
+
+// Keyed creates a collection with keys of type K, using a codec to convert them to and from the keys of the underlying collection c.
+func Keyed[K, T any](c Collection[T], codec KeyCodec[K]) *KeyedCollection[K, T] {
+	return &KeyedCollection[K, T]{
+		c:     c,
+		codec: codec,
+	}
+}
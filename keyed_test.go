@@ -0,0 +1,117 @@
+package ezdb
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func testKeyCodec[K comparable](t *testing.T, name string, codec KeyCodec[K], keys []K) {
+	encoded := []string{}
+	for _, key := range keys {
+		k, err := codec.Encode(key)
+		if err != nil {
+			t.Errorf("(%s) failed to encode %v (%q)", name, key, err)
+			continue
+		}
+		encoded = append(encoded, k)
+
+		if decoded, err := codec.Decode(k); err != nil {
+			t.Errorf("(%s) failed to decode %v (%q)", name, key, err)
+		} else if decoded != key {
+			t.Errorf("(%s) incorrect decoded key (expected %v, got %v)", name, key, decoded)
+		}
+	}
+
+	if !slices.IsSorted(encoded) {
+		t.Errorf("(%s) expected encoded keys to be in order", name)
+	}
+}
+
+func TestKeyCodec(t *testing.T) {
+	testKeyCodec(t, "int", IntKey[int64](), []int64{-1 << 63, -1000, -1, 0, 1, 255, 256, 1<<63 - 1})
+	testKeyCodec(t, "uint", UintKey[uint32](), []uint32{0, 1, 255, 256, 1<<32 - 1})
+	testKeyCodec(t, "string", StringKey[string](), []string{"a", "ab", "b"})
+	testKeyCodec(t, "uuid", UUIDKey[[16]byte](), [][16]byte{{0x01}, {0x01, 0x02}, {0xff}})
+
+	if _, err := IntKey[int8]().Decode("\x80\x00\x00\x00\x00\x00\x01\x00"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for out of range int8 (got %q)", err)
+	}
+	if _, err := UintKey[uint64]().Decode("short"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for short uint64 (got %q)", err)
+	}
+	if _, err := UUIDKey[[16]byte]().Decode("short"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for short UUID (got %q)", err)
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	path := ".leveldb/leveldb_keyed_test"
+	l := LevelDB[*Student](path, studentMarshaler, nil)
+	defer l.Destroy()
+
+	c := Keyed(l, IntKey[int]())
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	ids := []int{100, -5, 20, 3, 0}
+	for _, id := range ids {
+		if err := c.Put(id, &Student{Name: "Student", Age: id}); err != nil {
+			t.Fatalf("failed to put student %d (%q)", id, err)
+		}
+	}
+
+	if has, err := c.Has(20); err != nil || !has {
+		t.Errorf("expected collection to have student 20 (%v, %q)", has, err)
+	}
+	if value, err := c.Get(-5); err != nil {
+		t.Errorf("failed to get student -5 (%q)", err)
+	} else if value.Age != -5 {
+		t.Errorf("incorrect student (expected age -5, got %d)", value.Age)
+	}
+	if err := c.Delete(3); err != nil {
+		t.Errorf("failed to delete student 3 (%q)", err)
+	}
+
+	keys := []int{}
+	for key, value := range c.All() {
+		if key != value.Age {
+			t.Errorf("incorrect student %d (got age %d)", key, value.Age)
+		}
+		keys = append(keys, key)
+	}
+	if !slices.Equal(keys, []int{-5, 0, 20, 100}) {
+		t.Errorf("incorrect order of students (got %v)", keys)
+	}
+
+	// Keys that cannot be decoded are skipped by All and Keys, but reported by Results
+	if err := l.Put("short", &Student{Name: "Student"}); err != nil {
+		t.Fatalf("failed to put student with undecodable key (%q)", err)
+	}
+	if keys := slices.Collect(c.Keys()); !slices.Equal(keys, []int{-5, 0, 20, 100}) {
+		t.Errorf("incorrect keys with undecodable key (got %v)", keys)
+	}
+	failed := 0
+	for _, result := range c.Results() {
+		if err := result.Err; err != nil {
+			var docErr *DocumentError
+			if !errors.As(err, &docErr) || docErr.Key != "short" || !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("incorrect error for undecodable key (got %q)", err)
+			}
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("expected 1 error for undecodable key, got %d", failed)
+	}
+
+	i := c.Iter()
+	defer i.Release()
+	if !i.Last() {
+		t.Fatal("expected iterator to have a last student")
+	}
+	if key, err := c.Key(i); err != nil || key != 100 {
+		t.Errorf("incorrect last student (expected 100, got %d, %q)", key, err)
+	}
+}
@@ -1,12 +1,23 @@
 package ezdb
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"iter"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+// metaPrefix is the prefix of keys used internally, such as for sequences.
+// These keys are hidden from iterators and cannot be used for documents.
+const metaPrefix = "\x00ezdb:"
+
 type LevelDBCollection[T any] struct {
 	path string
 
@@ -16,6 +27,62 @@ type LevelDBCollection[T any] struct {
 	optOpen  *opt.Options
 	optRead  *opt.ReadOptions
 	optWrite *opt.WriteOptions
+
+	sortBudget int
+	sortDir    string
+
+	idGenerator IDGenerator
+	keyPolicy   *KeyPolicy
+	unique      []*uniqueConstraint[T]
+	validator   Validator[T]
+	hooks       hooks[T]
+
+	// Held while writing, so that read-modify-write operations such as Patch are atomic.
+	mu sync.Mutex
+}
+
+// AfterDelete registers a hook that is called after a document is deleted.
+// See Hook for details.
+func (c *LevelDBCollection[T]) AfterDelete(h Hook[T]) *LevelDBCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.afterDelete = append(c.hooks.afterDelete, h)
+	return c
+}
+
+// AfterPut registers a hook that is called after a document is put.
+// See Hook for details.
+func (c *LevelDBCollection[T]) AfterPut(h Hook[T]) *LevelDBCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.afterPut = append(c.hooks.afterPut, h)
+	return c
+}
+
+func (c *LevelDBCollection[T]) All() iter.Seq2[string, T] {
+	return collectionAll[T](c)
+}
+
+// BeforeDelete registers a hook that is called before a document is deleted, and can prevent it.
+// See Hook for details.
+func (c *LevelDBCollection[T]) BeforeDelete(h Hook[T]) *LevelDBCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.beforeDelete = append(c.hooks.beforeDelete, h)
+	return c
+}
+
+// BeforePut registers a hook that is called before a document is put, and can prevent it or modify the document.
+// See Hook for details.
+func (c *LevelDBCollection[T]) BeforePut(h Hook[T]) *LevelDBCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.beforePut = append(c.hooks.beforePut, h)
+	return c
 }
 
 func (c *LevelDBCollection[T]) Close() error {
@@ -31,7 +98,15 @@ func (c *LevelDBCollection[T]) Close() error {
 }
 
 func (c *LevelDBCollection[T]) Delete(key string) error {
-	return c.db.Delete([]byte(key), c.optWrite)
+	c.mu.Lock()
+	change, err := c.delete(key)
+	after := c.hooks.afterDelete
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return runHooks(after, change)
 }
 
 // Destroy the database completely, removing it from disk.
@@ -45,6 +120,9 @@ func (c *LevelDBCollection[T]) Destroy() error {
 
 func (c *LevelDBCollection[T]) Get(key string) (T, error) {
 	dest := c.m.Factory()
+	if err := checkDocumentKey(key); err != nil {
+		return dest, err
+	}
 
 	src, err := c.db.Get([]byte(key), c.optRead)
 	if err != nil {
@@ -57,42 +135,437 @@ func (c *LevelDBCollection[T]) Get(key string) (T, error) {
 }
 
 func (c *LevelDBCollection[T]) Has(key string) (bool, error) {
+	if err := checkDocumentKey(key); err != nil {
+		return false, err
+	}
 	return c.db.Has([]byte(key), c.optRead)
 }
 
+// Insert puts a document with a key from the collection's ID generator, returning the key.
+// If the generated key is already in use, another is generated.
+func (c *LevelDBCollection[T]) Insert(value T) (string, error) {
+	var change *Change[T]
+
+	c.mu.Lock()
+	key, err := insert(c.idGenerator, c.Has, func(key string) (err error) {
+		change, err = c.put(key, value)
+		return err
+	})
+	after := c.hooks.afterPut
+	c.mu.Unlock()
+	if err != nil {
+		return key, err
+	}
+
+	return key, runHooks(after, change)
+}
+
+// Iter creates an iterator over a snapshot of the collection.
+// Iterators derived from it by Limit, Prefix, Reverse, Skip and so on read the same snapshot, so they are not affected by later writes.
 func (c *LevelDBCollection[T]) Iter() Iterator[T] {
-	i := &LevelDBIterator[T]{
-		i: c.db.NewIterator(nil, c.optRead),
-		m: c.m,
+	if c.db == nil {
+		return newLevelDBIterator[T](levelDBUnreadable{err: ErrClosed}, c.m, nil, c.optRead, nil)
 	}
 
+	snap, err := c.db.GetSnapshot()
+	if err != nil {
+		return newLevelDBIterator[T](levelDBUnreadable{err: err}, c.m, nil, c.optRead, nil)
+	}
+
+	i := newLevelDBIterator[T](snap, c.m, nil, c.optRead, nil)
+	i.snap = snap
+	i.keyFilter = isDocumentKey
+	i.sortBudget = c.sortBudget
+	i.sortDir = c.sortDir
 	return i
 }
 
+func (c *LevelDBCollection[T]) Keys() iter.Seq[string] {
+	return collectionKeys[T](c)
+}
+
 func (c *LevelDBCollection[T]) Open() error {
-	if c.db == nil {
-		db, err := leveldb.OpenFile(c.path, c.optOpen)
-		if err != nil {
-			return err
-		}
+	if c.db != nil {
+		return nil
+	}
+
+	db, err := leveldb.OpenFile(c.path, c.optOpen)
+	if err != nil {
+		return err
+	}
+	c.db = db
 
-		c.db = db
+	if err := c.syncUnique(); err != nil {
+		// Close the database so that Open can be retried
+		c.db = nil
+		db.Close()
+		return err
 	}
 
 	return nil
 }
 
+// Patch applies a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) to a document.
+// The document is converted to JSON, patched and converted back, so T must support JSON encoding, although the collection may use any marshaler.
+//
+// The document is read and written atomically with respect to other writes to this collection.
+func (c *LevelDBCollection[T]) Patch(key string, patch []byte) error {
+	c.mu.Lock()
+	change, err := c.patch(key, patch)
+	after := c.hooks.afterPut
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return runHooks(after, change)
+}
+
 func (c *LevelDBCollection[T]) Put(key string, src T) error {
-	if err := ValidateKey(key); err != nil {
+	c.mu.Lock()
+	change, err := c.put(key, src)
+	after := c.hooks.afterPut
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return runHooks(after, change)
+}
+
+// Sequence creates a generator for monotonic numeric keys, which is persisted in the collection under the given name.
+// See SequenceGenerator for details.
+//
+// Numbers are reserved block at a time, and each reservation is synced to disk before any number in it is used, so numbers are never reused even after a crash.
+// Only one generator should be used for each name.
+func (c *LevelDBCollection[T]) Sequence(name string, block int) *SequenceGenerator {
+	return Sequence(&levelDBSequenceStore[T]{c: c, key: []byte(metaPrefix + "seq:" + name)}, block)
+}
+
+// SetIDGenerator sets the generator used by Insert.
+// By default, ULIDs are generated.
+func (c *LevelDBCollection[T]) SetIDGenerator(g IDGenerator) *LevelDBCollection[T] {
+	c.idGenerator = g
+	return c
+}
+
+func (c *LevelDBCollection[T]) Results() iter.Seq2[string, Result[T]] {
+	return collectionResults[T](c)
+}
+
+// SetKeyPolicy restricts the keys that can be used to put data into the collection.
+// If p is nil, only ValidateKey is applied.
+// Keys reserved for internal use are always rejected.
+//
+// The policy is only checked when data is put into the collection.
+// Keys already in the collection are not re-checked, so the policy should be set before the collection is used.
+func (c *LevelDBCollection[T]) SetKeyPolicy(p *KeyPolicy) *LevelDBCollection[T] {
+	c.keyPolicy = p
+	return c
+}
+
+// SetValidator sets a validator for documents put into the collection.
+// If T implements Validatable, documents are also validated by their own Validate method.
+// Put fails with a ValidationError if either finds a problem.
+func (c *LevelDBCollection[T]) SetValidator(v Validator[T]) *LevelDBCollection[T] {
+	c.validator = v
+	return c
+}
+
+// Unique requires a field to be unique among documents in the collection.
+// The function f extracts the field from a document. Documents with an empty value are not constrained.
+//
+// Put fails with ErrConstraint if another document already has the same value.
+// The document and its index entries are written in a single batch, so the index is always consistent with the documents.
+//
+// The constraints registered when the collection is opened are recorded in the database, and the index is only rebuilt from every document if a constraint is new or its version has changed.
+// Constraints should therefore be registered before opening the collection. The index is always rebuilt when a constraint is added to an open collection.
+// When the collection is opened, the indexes of constraints that are no longer registered are deleted.
+//
+// If documents already violate the constraint, ErrConstraint is returned and, if the collection is open, the constraint is not added.
+// The name must not be empty or contain a null byte, otherwise ErrInvalidConstraint is returned.
+func (c *LevelDBCollection[T]) Unique(name string, f func(T) string) error {
+	return c.UniqueVersion(name, 0, f)
+}
+
+// UniqueVersion is like Unique, but with a version that should be increased whenever f changes, so that the index is rebuilt when the collection is next opened.
+func (c *LevelDBCollection[T]) UniqueVersion(name string, version uint64, f func(T) string) error {
+	if err := checkConstraintName(name); err != nil {
 		return err
 	}
 
-	dest, err := c.m.Marshal(src)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u := &uniqueConstraint[T]{name: name, version: version, field: f}
+	if c.db != nil {
+		if err := c.reindex(u); err != nil {
+			return err
+		}
+	}
+
+	c.unique = append(c.unique, u)
+	return nil
+}
+
+func (c *LevelDBCollection[T]) Values() iter.Seq[T] {
+	return collectionValues[T](c)
+}
+
+// delete a document from the collection, calling before hooks and updating constraints.
+// The collection must be locked for writing.
+func (c *LevelDBCollection[T]) delete(key string) (*Change[T], error) {
+	if err := checkDocumentKey(key); err != nil {
+		return nil, err
+	}
+	change := &Change[T]{Key: key}
+
+	if len(c.unique) == 0 && !c.hooks.deletes() {
+		return change, c.db.Delete([]byte(key), c.optWrite)
+	}
+
+	old, err := c.old(key)
+	if err != nil {
+		return nil, err
+	}
+	if old != nil {
+		change.Old = *old
+		change.Exists = true
+	}
+
+	if err := runHooks(c.hooks.beforeDelete, change); err != nil {
+		return nil, err
+	}
+
+	batch := &leveldb.Batch{}
+	batch.Delete([]byte(key))
+	if err := c.indexUnique(batch, key, old, nil); err != nil {
+		return nil, err
+	}
+	return change, c.db.Write(batch, c.optWrite)
+}
+
+// generator gets the ID generator used by Insert, so that a WrappedCollection can use it too.
+func (c *LevelDBCollection[T]) generator() IDGenerator {
+	return c.idGenerator
+}
+
+// indexUnique adds changes to unique indexes to a batch, for a document changing from old to doc.
+// Either may be nil if the document is being created or deleted.
+// Returns ErrConstraint if doc has a unique value that is used by another document.
+func (c *LevelDBCollection[T]) indexUnique(batch *leveldb.Batch, key string, old, doc *T) error {
+	for _, u := range c.unique {
+		prev, next := u.value(old), u.value(doc)
+		if prev == next {
+			continue
+		}
+
+		if prev != "" {
+			owner, err := c.db.Get(uniqueIndexKey(u.name, prev), c.optRead)
+			if err == nil && string(owner) == key {
+				batch.Delete(uniqueIndexKey(u.name, prev))
+			} else if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+				return err
+			}
+		}
+
+		if next != "" {
+			owner, err := c.db.Get(uniqueIndexKey(u.name, next), c.optRead)
+			if err == nil && string(owner) != key {
+				// Only fail if the other document still has the value
+				if other, err := c.old(string(owner)); err != nil {
+					return err
+				} else if u.value(other) == next {
+					return constraintError(u.name, next, string(owner))
+				}
+			} else if err != nil && !errors.Is(err, leveldb.ErrNotFound) {
+				return err
+			}
+			batch.Put(uniqueIndexKey(u.name, next), []byte(key))
+		}
+	}
+	return nil
+}
+
+// old gets the current value of a document, or nil if it does not exist.
+// If the document cannot be unmarshaled, a DocumentError is returned, since its indexed values and the change passed to hooks would be unknown.
+func (c *LevelDBCollection[T]) old(key string) (*T, error) {
+	src, err := c.db.Get([]byte(key), c.optRead)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	value := c.m.Factory()
+	if err := c.m.Unmarshal(src, value); err != nil {
+		return nil, &DocumentError{Key: key, Err: err}
+	}
+	return &value, nil
+}
+
+// patch applies a patch to a document.
+// The collection must be locked for writing.
+func (c *LevelDBCollection[T]) patch(key string, patch []byte) (*Change[T], error) {
+	value, err := c.Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = patchValue(value, patch, c.m.Factory)
 	if err != nil {
+		return nil, err
+	}
+
+	return c.put(key, value)
+}
+
+// put a document into the collection, calling before hooks and checking constraints.
+// The collection must be locked for writing.
+func (c *LevelDBCollection[T]) put(key string, src T) (*Change[T], error) {
+	if err := c.keyPolicy.Check(key); err != nil {
+		return nil, err
+	}
+	if err := checkDocumentKey(key); err != nil {
+		return nil, err
+	}
+
+	change := &Change[T]{Key: key, New: src}
+
+	var old *T
+	if len(c.unique) > 0 || c.hooks.puts() {
+		var err error
+		if old, err = c.old(key); err != nil {
+			return nil, err
+		}
+		if old != nil {
+			change.Old = *old
+			change.Exists = true
+		}
+	}
+
+	if err := runHooks(c.hooks.beforePut, change); err != nil {
+		return nil, err
+	}
+
+	if err := validate(c.validator, key, change.New); err != nil {
+		return nil, err
+	}
+
+	dest, err := c.m.Marshal(change.New)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.unique) == 0 {
+		return change, c.db.Put([]byte(key), dest, c.optWrite)
+	}
+
+	batch := &leveldb.Batch{}
+	if err := c.indexUnique(batch, key, old, &change.New); err != nil {
+		return nil, err
+	}
+	batch.Put([]byte(key), dest)
+	return change, c.db.Write(batch, c.optWrite)
+}
+
+// reindex rebuilds the index for a unique constraint from every document.
+func (c *LevelDBCollection[T]) reindex(u *uniqueConstraint[T]) error {
+	batch := &leveldb.Batch{}
+
+	entries := c.db.NewIterator(util.BytesPrefix([]byte(uniqueIndexPrefix(u.name))), c.optRead)
+	for entries.Next() {
+		batch.Delete(append([]byte{}, entries.Key()...))
+	}
+	entries.Release()
+	if err := entries.Error(); err != nil {
+		return err
+	}
+
+	index := map[string]string{}
+	docs := c.Iter()
+	defer docs.Release()
+	for ok := docs.First(); ok; ok = docs.Next() {
+		key, value, err := docs.Get()
+		if err != nil {
+			return &DocumentError{Key: key, Err: err}
+		}
+
+		v := u.field(value)
+		if v == "" {
+			continue
+		}
+		if other, ok := index[v]; ok {
+			return constraintError(u.name, v, other)
+		}
+		index[v] = key
+		batch.Put(uniqueIndexKey(u.name, v), []byte(key))
+	}
+	if err := docs.Err(); err != nil {
+		return err
+	}
+
+	batch.Put(uniqueRecordKey(u.name), u.record())
+	return c.db.Write(batch, c.optWrite)
+}
+
+// syncUnique brings unique indexes up to date with the registered constraints when the collection is opened.
+// Indexes of constraints that are no longer registered are deleted, and indexes of constraints that are new or have changed are rebuilt.
+func (c *LevelDBCollection[T]) syncUnique() error {
+	registered := map[string]*uniqueConstraint[T]{}
+	for _, u := range c.unique {
+		registered[u.name] = u
+	}
+
+	batch := &leveldb.Batch{}
+
+	// Delete the records of constraints that are no longer registered, and find those that are unchanged
+	unchanged := map[string]bool{}
+	records := c.db.NewIterator(util.BytesPrefix([]byte(uniqueRecordPrefix)), c.optRead)
+	for records.Next() {
+		name := strings.TrimPrefix(string(records.Key()), uniqueRecordPrefix)
+		if u, ok := registered[name]; !ok {
+			batch.Delete(append([]byte{}, records.Key()...))
+		} else if bytes.Equal(records.Value(), u.record()) {
+			unchanged[name] = true
+		}
+	}
+	records.Release()
+	if err := records.Error(); err != nil {
+		return err
+	}
+
+	// Delete the entries of indexes that are no longer registered, seeking past those that are
+	entries := c.db.NewIterator(util.BytesPrefix([]byte(metaPrefix+"unique:")), c.optRead)
+	for ok := entries.First(); ok; {
+		name, _, _ := strings.Cut(strings.TrimPrefix(string(entries.Key()), metaPrefix+"unique:"), "\x00")
+		if registered[name] != nil {
+			ok = entries.Seek(util.BytesPrefix([]byte(uniqueIndexPrefix(name))).Limit)
+		} else {
+			batch.Delete(append([]byte{}, entries.Key()...))
+			ok = entries.Next()
+		}
+	}
+	entries.Release()
+	if err := entries.Error(); err != nil {
 		return err
 	}
 
-	return c.db.Put([]byte(key), dest, c.optWrite)
+	if batch.Len() > 0 {
+		if err := c.db.Write(batch, c.optWrite); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range c.unique {
+		if unchanged[u.name] {
+			continue
+		}
+		if err := c.reindex(u); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // LevelDB creates a new collection using LevelDB storage.
@@ -106,6 +579,47 @@ func LevelDB[T any](path string, m DocumentMarshaler[T, []byte], o *LevelDBOptio
 		optOpen:  o.GetOpen(),
 		optRead:  o.GetRead(),
 		optWrite: o.GetWrite(),
+
+		sortBudget: o.GetSortBudget(),
+		sortDir:    o.GetSortDir(),
+
+		idGenerator: ULID(),
 	}
 	return c
 }
+
+// levelDBSequenceStore saves the state of a sequence in a LevelDB collection.
+type levelDBSequenceStore[T any] struct {
+	c   *LevelDBCollection[T]
+	key []byte
+}
+
+func (s *levelDBSequenceStore[T]) Load() (uint64, error) {
+	if s.c.db == nil {
+		return 0, ErrClosed
+	}
+
+	v, err := s.c.db.Get(s.key, s.c.optRead)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(v) != 8 {
+		return 0, errors.New("malformed sequence")
+	}
+	return binary.BigEndian.Uint64(v), nil
+}
+
+func (s *levelDBSequenceStore[T]) Save(limit uint64) error {
+	if s.c.db == nil {
+		return ErrClosed
+	}
+
+	return s.c.db.Put(s.key, binary.BigEndian.AppendUint64(nil, limit), &opt.WriteOptions{Sync: true})
+}
+
+// isDocumentKey returns true if a key is not used internally.
+func isDocumentKey(key string) bool {
+	return !strings.HasPrefix(key, metaPrefix)
+}
@@ -1,10 +1,65 @@
 package ezdb
 
-import "github.com/syndtr/goleveldb/leveldb/iterator"
+import (
+	"bytes"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// RawFilterFunc processes a document's key and marshaled value, returning true if the document should be included.
+type RawFilterFunc func(key string, value []byte) bool
+
+// levelDBReader creates iterators over a LevelDB database or snapshot.
+type levelDBReader interface {
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+// levelDBUnreadable is a levelDBReader for a database that cannot be read, such as one that is closed.
+type levelDBUnreadable struct {
+	err error
+}
 
 type LevelDBIterator[T any] struct {
-	i iterator.Iterator
-	m DocumentMarshaler[T, []byte]
+	db levelDBReader
+	i  iterator.Iterator
+	m  DocumentMarshaler[T, []byte]
+	o  *opt.ReadOptions
+	r  *util.Range
+
+	// Filters applied as the underlying iterator moves, so filtered documents are never unmarshaled.
+	keyFilter KeyFilterFunc
+	rawFilter RawFilterFunc
+
+	// Window over the underlying iterator.
+	// A limit of -1 means there is no limit.
+	reverse bool
+	skip    int
+	limit   int
+
+	// Memory budget and directory for sorting documents on disk.
+	sortBudget int
+	sortDir    string
+
+	// Position within the underlying iterator, or -1 if not yet positioned.
+	// This is only tracked precisely if the iterator is windowed.
+	pos int
+
+	// Snapshot read by this iterator and those derived from it.
+	// This is only set on the first iterator, which releases it.
+	snap *leveldb.Snapshot
+
+	err  error
+	prev Iterator[T]
+}
+
+func (i *LevelDBIterator[T]) All() iter.Seq2[string, T] {
+	return iterAll[T](i, &i.err)
 }
 
 func (i *LevelDBIterator[T]) Count() int {
@@ -21,29 +76,49 @@ func (i *LevelDBIterator[T]) Count() int {
 	return n
 }
 
+func (i *LevelDBIterator[T]) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.i.Error()
+}
+
+// Filter creates a new iterator with a subset of documents.
+// If a document cannot be read, filtering stops there and Err on this iterator reports the error.
 func (i *LevelDBIterator[T]) Filter(f FilterFunc[T]) Iterator[T] {
+	k := []string{}
 	m := map[string]T{}
 
-	if i.First() {
-		key, value, err := i.Get()
-		if err == nil {
-			m[key] = value
-		}
-	}
-
-	for i.Next() {
+	i.err = nil
+	for ok := i.First(); ok; ok = i.Next() {
 		key, value, err := i.Get()
 		if err != nil {
-			continue
+			// Stop at the first document that cannot be read, as iterAll does, so that Err reports it
+			i.err = &DocumentError{Key: key, Err: err}
+			break
+		}
+		if f(key, value) {
+			k = append(k, key)
+			m[key] = value
 		}
-		m[key] = value
 	}
 
-	return newMemoryIterator(m, nil, i)
+	return newMemoryIterator(m, k, i)
 }
 
 func (i *LevelDBIterator[T]) First() bool {
-	return i.i.First()
+	i.pos = -1
+	if !i.rawFirst() {
+		return false
+	}
+
+	i.pos = 0
+	for i.pos < i.skip {
+		if !i.step() {
+			return false
+		}
+	}
+	return i.inWindow()
 }
 
 func (i *LevelDBIterator[T]) Get() (string, T, error) {
@@ -57,7 +132,7 @@ func (i *LevelDBIterator[T]) GetAll() (map[string]T, error) {
 	if i.First() {
 		key, value, err := i.Get()
 		if err != nil {
-			return values, err
+			return values, &DocumentError{Key: key, Err: err}
 		}
 		values[key] = value
 	}
@@ -65,7 +140,7 @@ func (i *LevelDBIterator[T]) GetAll() (map[string]T, error) {
 	for i.Next() {
 		key, value, err := i.Get()
 		if err != nil {
-			return values, err
+			return values, &DocumentError{Key: key, Err: err}
 		}
 		values[key] = value
 	}
@@ -88,39 +163,477 @@ func (i *LevelDBIterator[T]) GetAllKeys() []string {
 }
 
 func (i *LevelDBIterator[T]) Key() string {
+	if i.windowed() && !i.inWindow() {
+		return ""
+	}
 	return string(i.i.Key())
 }
 
+func (i *LevelDBIterator[T]) Keys() iter.Seq[string] {
+	return iterKeys[T](i, &i.err)
+}
+
+func (i *LevelDBIterator[T]) KeyFilter(f KeyFilterFunc) Iterator[T] {
+	d := i.derive()
+
+	if i.windowed() {
+		// The window applies before the filter, so find the keys within it first
+		keys := map[string]bool{}
+		for ok := i.First(); ok; ok = i.Next() {
+			if key := i.Key(); f(key) {
+				keys[key] = true
+			}
+		}
+
+		d.skip = 0
+		d.limit = -1
+		d.keyFilter = func(key string) bool {
+			return keys[key]
+		}
+		d.rawFilter = nil
+		return d
+	}
+
+	if prev := i.keyFilter; prev != nil {
+		d.keyFilter = func(key string) bool {
+			return prev(key) && f(key)
+		}
+	} else {
+		d.keyFilter = f
+	}
+	return d
+}
+
 func (i *LevelDBIterator[T]) Last() bool {
-	return i.i.Last()
+	if !i.windowed() {
+		i.pos = -1
+		if !i.rawLast() {
+			return false
+		}
+		i.pos = 0
+		return true
+	}
+
+	if i.limit >= 0 {
+		// Walk the window, which is bounded, to find its end
+		if !i.First() {
+			return false
+		}
+		for i.Next() {
+		}
+		return i.Prev()
+	}
+
+	i.pos = -1
+	if !i.rawLast() {
+		return false
+	}
+	i.locate()
+	return i.inWindow()
+}
+
+func (i *LevelDBIterator[T]) Limit(n int) Iterator[T] {
+	d := i.derive()
+	if n < 0 {
+		return d
+	}
+	if d.limit < 0 || n < d.limit {
+		d.limit = n
+	}
+	return d
 }
 
 func (i *LevelDBIterator[T]) Next() bool {
-	return i.i.Next()
+	if i.pos < 0 {
+		return i.First()
+	}
+
+	if !i.windowed() {
+		return i.rawNext()
+	}
+
+	// Do not move further than one document past the end of the window, so Prev can return to it
+	if i.limit >= 0 && i.pos >= i.skip+i.limit {
+		return false
+	}
+	if !i.step() {
+		return false
+	}
+	return i.inWindow()
 }
 
 func (i *LevelDBIterator[T]) Prev() bool {
-	return i.i.Prev()
+	if !i.windowed() {
+		if i.pos < 0 {
+			return false
+		}
+		if !i.rawPrev() {
+			i.pos = -1
+			return false
+		}
+		return true
+	}
+
+	if i.pos < i.skip {
+		return false
+	}
+	if !i.stepBack() {
+		return false
+	}
+	return i.inWindow()
+}
+
+func (i *LevelDBIterator[T]) Prefix(prefix string) Iterator[T] {
+	if i.windowed() {
+		return i.KeyFilter(func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		})
+	}
+
+	// Narrow the range of the underlying iterator so that only keys with the prefix are visited
+	return i.deriveRange(intersectRange(i.r, util.BytesPrefix([]byte(prefix))))
+}
+
+// RawFilter creates a new iterator with a subset of documents, selected by their marshaled values.
+// Documents that do not match are never unmarshaled.
+// Values are passed to f as stored by the collection's marshaler, so use FilterJSON for filters that expect plain JSON.
+// The previous iterator will not be affected.
+func (i *LevelDBIterator[T]) RawFilter(f RawFilterFunc) Iterator[T] {
+	d := i.derive()
+
+	if i.windowed() {
+		// The window applies before the filter, so find the keys within it first
+		keys := map[string]bool{}
+		for ok := i.First(); ok; ok = i.Next() {
+			if key := i.Key(); f(key, i.i.Value()) {
+				keys[key] = true
+			}
+		}
+
+		d.skip = 0
+		d.limit = -1
+		d.keyFilter = func(key string) bool {
+			return keys[key]
+		}
+		d.rawFilter = nil
+		return d
+	}
+
+	if prev := i.rawFilter; prev != nil {
+		d.rawFilter = func(key string, value []byte) bool {
+			return prev(key, value) && f(key, value)
+		}
+	} else {
+		d.rawFilter = f
+	}
+	return d
 }
 
 func (i *LevelDBIterator[T]) Release() {
 	i.i.Release()
+	if i.snap != nil {
+		i.snap.Release()
+	}
+
+	if i.prev != nil {
+		i.prev.Release()
+	}
+}
+
+// Reverse creates a new iterator with documents in reverse order.
+// If this iterator is windowed by Skip or Limit, every key in its range is counted to mirror the window, so this is O(n) in the size of the range.
+func (i *LevelDBIterator[T]) Reverse() Iterator[T] {
+	d := i.derive()
+	d.reverse = !i.reverse
+
+	if i.windowed() {
+		// Mirror the window, which requires knowing the number of documents underlying it
+		total := 0
+		for ok := d.rawFirst(); ok; ok = d.rawNext() {
+			total++
+		}
+
+		end := total
+		if i.limit >= 0 && i.skip+i.limit < total {
+			end = i.skip + i.limit
+		}
+		d.skip = total - end
+		d.limit = max(end-i.skip, 0)
+	}
+
+	return d
 }
 
+// Seek moves the iterator to the first document with a key greater than or equal to key, or less than or equal to key if reversed.
+// If the iterator is windowed by Skip or Limit, its position is found by counting keys from the start of its range, so each call is O(n) in the number of keys before key.
+func (i *LevelDBIterator[T]) Seek(key string) bool {
+	ok := i.rawSeek([]byte(key))
+
+	if !i.windowed() {
+		i.pos = 0
+		return ok
+	}
+
+	if ok {
+		i.locate()
+		if i.pos < i.skip {
+			return i.First()
+		}
+		if i.inWindow() {
+			return true
+		}
+	}
+
+	// Move past the end of the window, so Prev returns to its last document
+	if i.Last() {
+		i.Next()
+	}
+	return false
+}
+
+func (i *LevelDBIterator[T]) Skip(n int) Iterator[T] {
+	d := i.derive()
+	if n <= 0 {
+		return d
+	}
+	d.skip += n
+	if d.limit >= 0 {
+		d.limit = max(d.limit-n, 0)
+	}
+	return d
+}
+
+// Sort creates a new iterator with documents sorted by f.
+// If a document cannot be read, sorting stops there and Err on this iterator reports the error.
 func (i *LevelDBIterator[T]) Sort(f SortFunc[T]) Iterator[T] {
-	all, _ := i.GetAll()
+	if i.sortBudget > 0 {
+		return externalSort(i, f, i.sortBudget, i.sortDir)
+	}
+
+	var all map[string]T
+	all, i.err = i.GetAll()
 	m := newMemoryIterator(all, nil, i)
 	return m.Sort(f)
 }
 
+// SortKeys creates a new iterator with documents sorted by key using f.
+// If a document cannot be read, sorting stops there and Err on this iterator reports the error.
 func (i *LevelDBIterator[T]) SortKeys(f SortFunc[string]) Iterator[T] {
-	all, _ := i.GetAll()
+	var all map[string]T
+	all, i.err = i.GetAll()
 	m := newMemoryIterator(all, nil, i)
 	return m.SortKeys(f)
 }
 
+// TopK creates a new iterator with the first n sorted documents, without sorting every document.
+// If a document cannot be read, TopK stops there and Err on this iterator reports the error.
+func (i *LevelDBIterator[T]) TopK(n int, f SortFunc[T]) Iterator[T] {
+	top, err := topK[T](i, n, f)
+	i.err = err
+	return top
+}
+
 func (i *LevelDBIterator[T]) Value() (T, error) {
+	if !i.i.Valid() || (i.windowed() && !i.inWindow()) {
+		var value T
+		return value, nil
+	}
+
 	value := i.m.Factory()
 	err := i.m.Unmarshal(i.i.Value(), value)
 	return value, err
 }
+
+func (i *LevelDBIterator[T]) Values() iter.Seq[T] {
+	return iterValues[T](i, &i.err)
+}
+
+// deferValue copies the current marshaled value, so that it can be unmarshaled on another goroutine.
+func (i *LevelDBIterator[T]) deferValue() func() (T, error) {
+	raw := append([]byte{}, i.i.Value()...)
+	return func() (T, error) {
+		value := i.m.Factory()
+		err := i.m.Unmarshal(raw, value)
+		return value, err
+	}
+}
+
+// derive creates a new iterator over the same range, with the same filter and window.
+// It reads the same snapshot as this iterator.
+// Releasing the new iterator also releases this one.
+func (i *LevelDBIterator[T]) derive() *LevelDBIterator[T] {
+	return i.deriveRange(i.r)
+}
+
+// deriveRange creates a new iterator over a different range, with the same filter and window.
+// Releasing the new iterator also releases this one.
+func (i *LevelDBIterator[T]) deriveRange(r *util.Range) *LevelDBIterator[T] {
+	d := newLevelDBIterator(i.db, i.m, r, i.o, i)
+	d.keyFilter = i.keyFilter
+	d.rawFilter = i.rawFilter
+	d.reverse = i.reverse
+	d.skip = i.skip
+	d.limit = i.limit
+	d.sortBudget = i.sortBudget
+	d.sortDir = i.sortDir
+	return d
+}
+
+// filterJSON filters documents by their marshaled values, which must be plain JSON.
+func (i *LevelDBIterator[T]) filterJSON(f RawFilterFunc) (Iterator[T], error) {
+	if _, ok := i.m.(*JSONMarshaler[T]); !ok {
+		return newMemoryIterator(map[string]T{}, []string{}, i), fmt.Errorf("%w: collection uses %T", ErrNotJSON, i.m)
+	}
+	return i.RawFilter(f), nil
+}
+
+func (i *LevelDBIterator[T]) inWindow() bool {
+	return i.pos >= i.skip && (i.limit < 0 || i.pos < i.skip+i.limit)
+}
+
+// locate sets the position of the iterator by counting from the start.
+// Only keys are read, so this is relatively cheap.
+func (i *LevelDBIterator[T]) locate() {
+	key := append([]byte{}, i.i.Key()...)
+
+	n := 0
+	ok := i.rawFirst()
+	for ok && !bytes.Equal(i.i.Key(), key) {
+		ok = i.rawNext()
+		n++
+	}
+	i.pos = n
+}
+
+// filterBackward moves the underlying iterator backward until it reaches a document that passes the filters.
+func (i *LevelDBIterator[T]) filterBackward(ok bool) bool {
+	for ok && !i.match() {
+		ok = i.i.Prev()
+	}
+	return ok
+}
+
+// filterForward moves the underlying iterator forward until it reaches a document that passes the filters.
+func (i *LevelDBIterator[T]) filterForward(ok bool) bool {
+	for ok && !i.match() {
+		ok = i.i.Next()
+	}
+	return ok
+}
+
+func (i *LevelDBIterator[T]) match() bool {
+	if i.keyFilter == nil && i.rawFilter == nil {
+		return true
+	}
+
+	key := string(i.i.Key())
+	if i.keyFilter != nil && !i.keyFilter(key) {
+		return false
+	}
+	return i.rawFilter == nil || i.rawFilter(key, i.i.Value())
+}
+
+func (i *LevelDBIterator[T]) rawFirst() bool {
+	if i.reverse {
+		return i.filterBackward(i.i.Last())
+	}
+	return i.filterForward(i.i.First())
+}
+
+func (i *LevelDBIterator[T]) rawLast() bool {
+	if i.reverse {
+		return i.filterForward(i.i.First())
+	}
+	return i.filterBackward(i.i.Last())
+}
+
+func (i *LevelDBIterator[T]) rawNext() bool {
+	if i.reverse {
+		return i.filterBackward(i.i.Prev())
+	}
+	return i.filterForward(i.i.Next())
+}
+
+func (i *LevelDBIterator[T]) rawPrev() bool {
+	if i.reverse {
+		return i.filterForward(i.i.Next())
+	}
+	return i.filterBackward(i.i.Prev())
+}
+
+func (i *LevelDBIterator[T]) rawSeek(key []byte) bool {
+	if !i.reverse {
+		return i.filterForward(i.i.Seek(key))
+	}
+
+	if !i.i.Seek(key) {
+		return i.filterBackward(i.i.Last())
+	}
+	if bytes.Equal(i.i.Key(), key) && i.match() {
+		return true
+	}
+	return i.filterBackward(i.i.Prev())
+}
+
+// step moves to the next document, tracking position.
+// If there is no next document, the position is one past the end, however many times step is called.
+func (i *LevelDBIterator[T]) step() bool {
+	valid := i.i.Valid()
+	if i.rawNext() || valid {
+		i.pos++
+	}
+	return i.i.Valid()
+}
+
+// stepBack moves to the previous document, tracking position.
+// If there is no previous document, the position is one before the start, however many times stepBack is called.
+func (i *LevelDBIterator[T]) stepBack() bool {
+	valid := i.i.Valid()
+	if i.rawPrev() || valid {
+		i.pos--
+	}
+	return i.i.Valid()
+}
+
+func (i *LevelDBIterator[T]) windowed() bool {
+	return i.skip > 0 || i.limit >= 0
+}
+
+// intersectRange gets the intersection of two key ranges.
+// A nil range includes all keys.
+func intersectRange(a, b *util.Range) *util.Range {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	r := &util.Range{Start: a.Start, Limit: a.Limit}
+	if bytes.Compare(b.Start, r.Start) > 0 {
+		r.Start = b.Start
+	}
+	if r.Limit == nil || (b.Limit != nil && bytes.Compare(b.Limit, r.Limit) < 0) {
+		r.Limit = b.Limit
+	}
+	return r
+}
+
+func (r levelDBUnreadable) NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator {
+	return iterator.NewEmptyIterator(r.err)
+}
+
+func newLevelDBIterator[T any](db levelDBReader, m DocumentMarshaler[T, []byte], r *util.Range, o *opt.ReadOptions, prev Iterator[T]) *LevelDBIterator[T] {
+	return &LevelDBIterator[T]{
+		db: db,
+		i:  db.NewIterator(r, o),
+		m:  m,
+		o:  o,
+		r:  r,
+
+		limit: -1,
+		pos:   -1,
+
+		prev: prev,
+	}
+}
@@ -6,6 +6,15 @@ type LevelDBOptions struct {
 	Open  *opt.Options
 	Read  *opt.ReadOptions
 	Write *opt.WriteOptions
+
+	// SortBudget limits the marshaled size, in bytes, of documents held in memory when sorting an iterator.
+	// Documents are also held in unmarshaled form, which is not counted, so actual memory use may be several times the budget.
+	// Documents exceeding the budget are sorted in runs on disk and merged as the sorted iterator is read.
+	// If zero, documents are always sorted in memory.
+	SortBudget int
+	// SortDir is the directory in which sorted runs are created.
+	// If empty, the default directory for temporary files is used.
+	SortDir string
 }
 
 func (o *LevelDBOptions) GetOpen() *opt.Options {
@@ -28,3 +37,17 @@ func (o *LevelDBOptions) GetWrite() *opt.WriteOptions {
 	}
 	return o.Write
 }
+
+func (o *LevelDBOptions) GetSortBudget() int {
+	if o == nil {
+		return 0
+	}
+	return o.SortBudget
+}
+
+func (o *LevelDBOptions) GetSortDir() string {
+	if o == nil {
+		return ""
+	}
+	return o.SortDir
+}
@@ -1,6 +1,12 @@
 package ezdb
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestLevelDB(t *testing.T) {
 	path := ".leveldb/leveldb_test"
@@ -25,3 +31,257 @@ func TestLevelDB(t *testing.T) {
 
 	fixture.Run()
 }
+
+func TestLevelDBWindow(t *testing.T) {
+	path := ".leveldb/leveldb_window_test"
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	// LevelDB iterators are natively sorted by key, so windows can be applied without sorting
+	testIterWindow(t, c.Iter)
+}
+
+func TestLevelDBSnapshot(t *testing.T) {
+	path := ".leveldb/leveldb_snapshot_test"
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	base := c.Iter()
+	defer base.Release()
+	if err := c.Put("zed", &Student{Name: "Zed", Age: 19}); err != nil {
+		t.Fatalf("failed to put student (%q)", err)
+	}
+
+	// Iterators derived after a write must read the same snapshot as the iterator they are derived from
+	tests := map[string]Iterator[*Student]{
+		"base":    base,
+		"limit":   base.Limit(10),
+		"skip":    base.Skip(0),
+		"prefix":  base.Prefix(""),
+		"reverse": base.Reverse().Reverse(),
+	}
+	for name, iter := range tests {
+		if keys := strings.Join(iter.GetAllKeys(), ","); keys != "annie,ben,clive" {
+			t.Errorf("(%s) incorrect students in snapshot (expected 'annie,ben,clive', got '%s')", name, keys)
+		}
+	}
+
+	if n := c.Iter().Count(); n != 4 {
+		t.Errorf("incorrect count of students in new iterator (expected 4, got %d)", n)
+	}
+}
+
+// countingMarshaler counts documents unmarshaled by another marshaler.
+type countingMarshaler[T any] struct {
+	DocumentMarshaler[T, []byte]
+
+	n int
+}
+
+func (m *countingMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	m.n++
+	return m.DocumentMarshaler.Unmarshal(src, dest)
+}
+
+func TestLevelDBKeyFilter(t *testing.T) {
+	m := &countingMarshaler[*Student]{DocumentMarshaler: studentMarshaler}
+
+	path := ".leveldb/leveldb_key_filter_test"
+	c := LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	iter := c.Iter().Prefix("b").KeyFilter(func(key string) bool {
+		return key != "bob"
+	})
+	defer iter.Release()
+
+	if n := iter.Count(); n != 1 {
+		t.Errorf("incorrect count of students (expected 1, got %d)", n)
+	}
+	if keys := iter.GetAllKeys(); len(keys) != 1 || keys[0] != "ben" {
+		t.Errorf("incorrect students (expected [ben], got %v)", keys)
+	}
+	if m.n != 0 {
+		t.Errorf("expected no students to be unmarshaled, got %d", m.n)
+	}
+}
+
+func TestLevelDBFilterError(t *testing.T) {
+	path := ".leveldb/leveldb_filter_error_test"
+	raw := LevelDB[[]byte](path, Bytes(), nil)
+	if err := raw.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer raw.Destroy()
+
+	if err := raw.Put("annie", []byte("not a student")); err != nil {
+		t.Fatalf("failed to put invalid student (%q)", err)
+	}
+	if err := raw.Close(); err != nil {
+		t.Fatalf("failed to close collection (%q)", err)
+	}
+
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Close()
+
+	iter := c.Iter()
+	filtered := iter.Filter(func(key string, value *Student) bool {
+		return true
+	})
+	defer filtered.Release()
+
+	var docErr *DocumentError
+	if err := iter.Err(); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected document error for 'annie', got %v", err)
+	}
+
+	// Sorting reports the error in the same way
+	sorted := c.Iter()
+	defer sorted.Release()
+	sorted.SortKeys(func(a, b string) bool { return a < b })
+	if err := sorted.Err(); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected document error for 'annie' sorting keys, got %v", err)
+	}
+	sorted.Sort(func(a, b *Student) bool { return a.Age < b.Age })
+	if err := sorted.Err(); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected document error for 'annie' sorting, got %v", err)
+	}
+
+	// Stream iterators report the error in the same way
+	stream := Union(c.Iter())
+	defer stream.Release()
+	filtered = stream.Filter(func(key string, value *Student) bool {
+		return true
+	})
+	if n := filtered.Count(); n != 0 {
+		t.Errorf("expected filtered stream to end at 'annie', got %d students", n)
+	}
+	if err := filtered.Err(); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected document error for 'annie' filtering stream, got %v", err)
+	}
+	stream.SortKeys(func(a, b string) bool { return a < b })
+	if err := stream.Err(); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected document error for 'annie' sorting stream, got %v", err)
+	}
+
+	// TopK must not skip documents it cannot read
+	top := c.Iter()
+	defer top.Release()
+	top.TopK(1, func(a, b *Student) bool { return a.Age < b.Age })
+	if err := top.Err(); !errors.As(err, &docErr) || docErr.Key != "annie" {
+		t.Errorf("expected document error for 'annie' selecting top students, got %v", err)
+	}
+}
+
+func TestLevelDBExternalSort(t *testing.T) {
+	dir := t.TempDir()
+
+	// Limit the fan-in so that runs are merged in several passes
+	fanIn := sortMergeFanIn
+	sortMergeFanIn = 4
+	defer func() {
+		sortMergeFanIn = fanIn
+	}()
+
+	path := ".leveldb/leveldb_sort_test"
+	c := LevelDB[*Student](path, studentMarshaler, &LevelDBOptions{
+		SortBudget: 1000,
+		SortDir:    dir,
+	})
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	total := 500
+	for n := 0; n < total; n++ {
+		key := fmt.Sprintf("student-%03d", n)
+		if err := c.Put(key, &Student{Name: key, Age: (n * 37) % 100}); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	iter := c.Iter().Sort(func(a, b *Student) bool {
+		return a.Age < b.Age
+	})
+
+	if runs, _ := os.ReadDir(dir); len(runs) != 1 {
+		t.Errorf("expected sort directory to be created, got %d entries", len(runs))
+	}
+
+	prev := &Student{Age: -1}
+	n := 0
+	for ok := iter.First(); ok; ok = iter.Next() {
+		key, value, err := iter.Get()
+		if err != nil {
+			t.Fatalf("failed to get sorted student '%s' (%q)", key, err)
+		}
+		if value.Age < prev.Age {
+			t.Errorf("student '%s' sorted out of order (age %d after %d)", key, value.Age, prev.Age)
+		} else if value.Age == prev.Age && value.Name < prev.Name {
+			t.Errorf("student '%s' sorted unstably (after '%s')", key, prev.Name)
+		}
+		prev = value
+		n++
+	}
+	if n != total {
+		t.Errorf("incorrect count of sorted students (expected %d, got %d)", total, n)
+	}
+
+	if !iter.Last() || !iter.Prev() {
+		t.Error("expected to move backward through sorted students")
+	} else if value, _ := iter.Value(); value.Age != 99 {
+		t.Errorf("incorrect age of second-last sorted student (expected 99, got %d)", value.Age)
+	}
+
+	top := iter.Limit(3).GetAllKeys()
+	if len(top) != 3 || top[0] != "student-000" {
+		t.Errorf("incorrect first sorted students (got %v)", top)
+	}
+
+	// Seeking a reversed sorted iterator finds the first key less than or equal to the target
+	byName := c.Iter().Sort(func(a, b *Student) bool {
+		return a.Name < b.Name
+	}).Reverse()
+	if !byName.Seek("student-100x") || byName.Key() != "student-100" {
+		t.Errorf("incorrect student after reverse seek (expected 'student-100', got '%s')", byName.Key())
+	} else if !byName.Next() || byName.Key() != "student-099" {
+		t.Errorf("incorrect student after reverse seek and next (expected 'student-099', got '%s')", byName.Key())
+	}
+	byName.Release()
+
+	iter.Release()
+	if runs, _ := os.ReadDir(dir); len(runs) != 0 {
+		t.Errorf("expected sort directory to be removed after release, got %d entries", len(runs))
+	}
+}
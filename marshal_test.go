@@ -0,0 +1,75 @@
+package ezdb
+
+import "testing"
+
+func newStudent() *Student {
+	return &Student{}
+}
+
+// Marshalers that should round-trip the Student fixtures.
+var studentMarshalers = map[string]DocumentMarshaler[*Student, []byte]{
+	"cbor":    CBOR(newStudent),
+	"gob":     Gob(newStudent),
+	"json":    studentMarshaler,
+	"msgpack": MsgPack(newStudent),
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for name, m := range studentMarshalers {
+		for key, expected := range students {
+			t.Logf("(%s) marshaling student '%s'", name, key)
+			b, err := m.Marshal(expected)
+			if err != nil {
+				t.Errorf("(%s) failed to marshal student '%s' (%q)", name, key, err)
+				continue
+			}
+
+			actual := m.Factory()
+			if err := m.Unmarshal(b, actual); err != nil {
+				t.Errorf("(%s) failed to unmarshal student '%s' (%q)", name, key, err)
+			} else if err := compareStudent(key, expected, actual); err != nil {
+				t.Errorf("(%s) %v", name, err)
+			}
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	for _, name := range []string{"json", "gob", "msgpack", "cbor"} {
+		m := studentMarshalers[name]
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for key, value := range students {
+					if _, err := m.Marshal(value); err != nil {
+						b.Fatalf("failed to marshal student '%s' (%q)", key, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	for _, name := range []string{"json", "gob", "msgpack", "cbor"} {
+		m := studentMarshalers[name]
+
+		marshaled := map[string][]byte{}
+		for key, value := range students {
+			data, err := m.Marshal(value)
+			if err != nil {
+				b.Fatalf("failed to marshal student '%s' (%q)", key, err)
+			}
+			marshaled[key] = data
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				for key, data := range marshaled {
+					if err := m.Unmarshal(data, m.Factory()); err != nil {
+						b.Fatalf("failed to unmarshal student '%s' (%q)", key, err)
+					}
+				}
+			}
+		})
+	}
+}
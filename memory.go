@@ -1,13 +1,75 @@
 package ezdb
 
+import (
+	"iter"
+	"maps"
+	"sync"
+)
+
 type MemoryCollection[T any] struct {
 	c Collection[T]
 	m map[string]T
 
+	idGenerator IDGenerator
+	keyPolicy   *KeyPolicy
+	unique      []*uniqueConstraint[T]
+	validator   Validator[T]
+	hooks       hooks[T]
+
 	open bool
+
+	// Held for writing while documents are written, so that constraints and before hooks are checked atomically, and for reading while documents are read.
+	mu sync.RWMutex
+}
+
+// AfterDelete registers a hook that is called after a document is deleted.
+// See Hook for details.
+func (c *MemoryCollection[T]) AfterDelete(h Hook[T]) *MemoryCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.afterDelete = append(c.hooks.afterDelete, h)
+	return c
+}
+
+// AfterPut registers a hook that is called after a document is put.
+// See Hook for details.
+func (c *MemoryCollection[T]) AfterPut(h Hook[T]) *MemoryCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.afterPut = append(c.hooks.afterPut, h)
+	return c
+}
+
+func (c *MemoryCollection[T]) All() iter.Seq2[string, T] {
+	return collectionAll[T](c)
+}
+
+// BeforeDelete registers a hook that is called before a document is deleted, and can prevent it.
+// See Hook for details.
+func (c *MemoryCollection[T]) BeforeDelete(h Hook[T]) *MemoryCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.beforeDelete = append(c.hooks.beforeDelete, h)
+	return c
+}
+
+// BeforePut registers a hook that is called before a document is put, and can prevent it or modify the document.
+// See Hook for details.
+func (c *MemoryCollection[T]) BeforePut(h Hook[T]) *MemoryCollection[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hooks.beforePut = append(c.hooks.beforePut, h)
+	return c
 }
 
 func (c *MemoryCollection[T]) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.c != nil {
 		return c.c.Close()
 	}
@@ -19,52 +81,70 @@ func (c *MemoryCollection[T]) Close() error {
 }
 
 func (c *MemoryCollection[T]) Delete(key string) error {
-	if !c.open {
-		return ErrClosed
-	}
-
-	if c.c != nil {
-		if err := c.c.Delete(key); err != nil {
-			return err
-		}
+	c.mu.Lock()
+	change, err := c.delete(key)
+	after := c.hooks.afterDelete
+	c.mu.Unlock()
+	if err != nil {
+		return err
 	}
 
-	delete(c.m, key)
-
-	return nil
+	return runHooks(after, change)
 }
 
 func (c *MemoryCollection[T]) Get(key string) (T, error) {
-	if !c.open {
-		return c.m[""], ErrClosed
-	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	if value, ok := c.m[key]; ok {
-		return value, nil
-	}
-
-	return c.m[""], ErrNotFound
+	return c.get(key)
 }
 
 func (c *MemoryCollection[T]) Has(key string) (bool, error) {
-	if !c.open {
-		return false, ErrClosed
-	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	_, ok := c.m[key]
+	return c.has(key)
+}
 
-	return ok, nil
+// Insert puts a document with a key from the collection's ID generator, returning the key.
+// If the generated key is already in use, another is generated.
+func (c *MemoryCollection[T]) Insert(value T) (string, error) {
+	var change *Change[T]
+
+	c.mu.Lock()
+	key, err := insert(c.idGenerator, c.has, func(key string) (err error) {
+		change, err = c.put(key, value)
+		return err
+	})
+	after := c.hooks.afterPut
+	c.mu.Unlock()
+	if err != nil {
+		return key, err
+	}
+
+	return key, runHooks(after, change)
 }
 
+// Iter creates an iterator over a copy of the documents in the collection, so that it is unaffected by later writes.
 func (c *MemoryCollection[T]) Iter() Iterator[T] {
-	m := newMemoryIterator[T](c.m, nil, nil)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	m := newMemoryIterator[T](maps.Clone(c.m), nil, nil)
 	if !c.open {
 		m.Release()
 	}
 	return m
 }
 
+func (c *MemoryCollection[T]) Keys() iter.Seq[string] {
+	return collectionKeys[T](c)
+}
+
 func (c *MemoryCollection[T]) Open() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.c != nil {
 		if err := c.c.Open(); err != nil {
 			return err
@@ -83,29 +163,236 @@ func (c *MemoryCollection[T]) Open() error {
 		c.m = map[string]T{}
 	}
 
+	for _, u := range c.unique {
+		if err := u.reindex(c.m); err != nil {
+			return err
+		}
+	}
+
 	c.open = true
 
 	return nil
 }
 
+// Patch applies a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) to a document.
+// The document is converted to JSON, patched and converted back to a new value, so T must support JSON encoding.
+// The previous value is not modified.
+func (c *MemoryCollection[T]) Patch(key string, patch []byte) error {
+	c.mu.Lock()
+	change, err := c.patch(key, patch)
+	after := c.hooks.afterPut
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return runHooks(after, change)
+}
+
 func (c *MemoryCollection[T]) Put(key string, value T) error {
-	if !c.open {
-		return ErrClosed
+	c.mu.Lock()
+	change, err := c.put(key, value)
+	after := c.hooks.afterPut
+	c.mu.Unlock()
+	if err != nil {
+		return err
 	}
 
-	if err := ValidateKey(key); err != nil {
+	return runHooks(after, change)
+}
+
+func (c *MemoryCollection[T]) Results() iter.Seq2[string, Result[T]] {
+	return collectionResults[T](c)
+}
+
+// SetIDGenerator sets the generator used by Insert.
+// By default, ULIDs are generated.
+func (c *MemoryCollection[T]) SetIDGenerator(g IDGenerator) *MemoryCollection[T] {
+	c.idGenerator = g
+	return c
+}
+
+// SetKeyPolicy restricts the keys that can be used to put data into the collection.
+// If p is nil, only ValidateKey is applied.
+//
+// The policy is only checked when data is put into the collection.
+// Keys already in the collection, including those loaded from a persistence backend by Open, are not re-checked, so the policy should be set before the collection is used.
+func (c *MemoryCollection[T]) SetKeyPolicy(p *KeyPolicy) *MemoryCollection[T] {
+	c.keyPolicy = p
+	return c
+}
+
+// SetValidator sets a validator for documents put into the collection.
+// If T implements Validatable, documents are also validated by their own Validate method.
+// Put fails with a ValidationError if either finds a problem.
+func (c *MemoryCollection[T]) SetValidator(v Validator[T]) *MemoryCollection[T] {
+	c.validator = v
+	return c
+}
+
+// Unique requires a field to be unique among documents in the collection.
+// The function f extracts the field from a document. Documents with an empty value are not constrained.
+//
+// Put fails with ErrConstraint if another document already has the same value.
+// If the collection is open and its documents already violate the constraint, Unique returns ErrConstraint and the constraint is not added.
+// The name must not be empty or contain a null byte, otherwise ErrInvalidConstraint is returned.
+func (c *MemoryCollection[T]) Unique(name string, f func(T) string) error {
+	if err := checkConstraintName(name); err != nil {
 		return err
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u := &uniqueConstraint[T]{name: name, field: f, index: map[string]string{}}
+	if c.open {
+		if err := u.reindex(c.m); err != nil {
+			return err
+		}
+	}
+
+	c.unique = append(c.unique, u)
+	return nil
+}
+
+func (c *MemoryCollection[T]) Values() iter.Seq[T] {
+	return collectionValues[T](c)
+}
+
+// delete a document from the collection, calling before hooks and updating constraints.
+// The collection must be locked for writing.
+func (c *MemoryCollection[T]) delete(key string) (*Change[T], error) {
+	if !c.open {
+		return nil, ErrClosed
+	}
+
+	old, exists := c.m[key]
+	change := &Change[T]{Key: key, Old: old, Exists: exists}
+
+	if err := runHooks(c.hooks.beforeDelete, change); err != nil {
+		return nil, err
+	}
+
+	if c.c != nil {
+		if err := c.c.Delete(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if exists {
+		for _, u := range c.unique {
+			if v := u.field(old); v != "" && u.index[v] == key {
+				delete(u.index, v)
+			}
+		}
+	}
+
+	delete(c.m, key)
+
+	return change, nil
+}
+
+// generator gets the ID generator used by Insert, so that a WrappedCollection can use it too.
+func (c *MemoryCollection[T]) generator() IDGenerator {
+	return c.idGenerator
+}
+
+// get a document from the collection.
+// The collection must be locked for reading.
+func (c *MemoryCollection[T]) get(key string) (T, error) {
+	if !c.open {
+		return c.m[""], ErrClosed
+	}
+
+	if value, ok := c.m[key]; ok {
+		return value, nil
+	}
+
+	return c.m[""], ErrNotFound
+}
+
+// has checks whether a document exists in the collection.
+// The collection must be locked for reading.
+func (c *MemoryCollection[T]) has(key string) (bool, error) {
+	if !c.open {
+		return false, ErrClosed
+	}
+
+	_, ok := c.m[key]
+
+	return ok, nil
+}
+
+// patch applies a patch to a document.
+// The collection must be locked for writing.
+func (c *MemoryCollection[T]) patch(key string, patch []byte) (*Change[T], error) {
+	value, err := c.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = patchValue(value, patch, func() T {
+		var zero T
+		return zero
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.put(key, value)
+}
+
+// put a document into the collection, calling before hooks and checking constraints.
+// The collection must be locked for writing.
+func (c *MemoryCollection[T]) put(key string, value T) (*Change[T], error) {
+	if !c.open {
+		return nil, ErrClosed
+	}
+
+	if err := c.keyPolicy.Check(key); err != nil {
+		return nil, err
+	}
+
+	old, exists := c.m[key]
+	change := &Change[T]{Key: key, Old: old, Exists: exists, New: value}
+
+	if err := runHooks(c.hooks.beforePut, change); err != nil {
+		return nil, err
+	}
+	value = change.New
+
+	if err := validate(c.validator, key, value); err != nil {
+		return nil, err
+	}
+
+	for _, u := range c.unique {
+		if v := u.field(value); v != "" {
+			if other, ok := u.index[v]; ok && other != key {
+				return nil, constraintError(u.name, v, other)
+			}
+		}
+	}
+
 	if c.c != nil {
 		if err := c.c.Put(key, value); err != nil {
-			return err
+			return nil, err
+		}
+	}
+
+	for _, u := range c.unique {
+		if exists {
+			if v := u.field(old); v != "" && u.index[v] == key {
+				delete(u.index, v)
+			}
+		}
+		if v := u.field(value); v != "" {
+			u.index[v] = key
 		}
 	}
 
 	c.m[key] = value
 
-	return nil
+	return change, nil
 }
 
 // Memory creates an in-memory collection, which offers fast access without a document marshaler.
@@ -117,5 +404,7 @@ func Memory[T any](c Collection[T]) *MemoryCollection[T] {
 	return &MemoryCollection[T]{
 		c: c,
 		m: map[string]T{},
+
+		idGenerator: ULID(),
 	}
 }
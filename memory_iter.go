@@ -1,21 +1,35 @@
 package ezdb
 
-import "sort"
+import (
+	"iter"
+	"sort"
+	"strings"
+)
 
 type MemoryIterator[T any] struct {
 	k []string
 	m map[string]T
 
+	desc     bool
+	err      error
 	pos      int
 	released bool
 
 	prev Iterator[T]
 }
 
+func (i *MemoryIterator[T]) All() iter.Seq2[string, T] {
+	return iterAll[T](i, &i.err)
+}
+
 func (i *MemoryIterator[T]) Count() int {
 	return len(i.k)
 }
 
+func (i *MemoryIterator[T]) Err() error {
+	return i.err
+}
+
 func (i *MemoryIterator[T]) Filter(f FilterFunc[T]) Iterator[T] {
 	if i.released {
 		return i
@@ -31,11 +45,13 @@ func (i *MemoryIterator[T]) Filter(f FilterFunc[T]) Iterator[T] {
 			m[key] = value
 		}
 	}
-	return newMemoryIterator(m, k, i)
+	r := newMemoryIterator(m, k, i)
+	r.desc = i.desc
+	return r
 }
 
 func (i *MemoryIterator[T]) First() bool {
-	if i.released {
+	if i.released || len(i.k) == 0 {
 		return false
 	}
 	i.pos = 0
@@ -79,30 +95,67 @@ func (i *MemoryIterator[T]) GetAllKeys() []string {
 }
 
 func (i *MemoryIterator[T]) Key() string {
-	if i.pos < 0 || i.pos > i.Count() || i.released {
+	if i.pos < 0 || i.pos >= i.Count() || i.released {
 		return ""
 	}
 	return i.k[i.pos]
 }
 
-func (i *MemoryIterator[T]) Last() bool {
+func (i *MemoryIterator[T]) Keys() iter.Seq[string] {
+	return iterKeys[T](i, &i.err)
+}
+
+func (i *MemoryIterator[T]) KeyFilter(f KeyFilterFunc) Iterator[T] {
 	if i.released {
+		return i
+	}
+
+	k := []string{}
+	for _, key := range i.k {
+		if f(key) {
+			k = append(k, key)
+		}
+	}
+
+	r := newMemoryIterator(i.m, k, i)
+	r.desc = i.desc
+	return r
+}
+
+func (i *MemoryIterator[T]) Last() bool {
+	if i.released || len(i.k) == 0 {
 		return false
 	}
 	i.pos = len(i.k) - 1
 	return true
 }
 
+func (i *MemoryIterator[T]) Limit(n int) Iterator[T] {
+	if i.released {
+		return i
+	}
+
+	k := i.k
+	if n >= 0 && n < len(k) {
+		k = k[:n]
+	}
+
+	l := newMemoryIterator(i.m, append([]string{}, k...), i)
+	l.desc = i.desc
+	return l
+}
+
 func (i *MemoryIterator[T]) Next() bool {
 	if i.released {
 		return false
 	}
 
-	hasNext := i.pos+1 <= i.Count()
-	if hasNext {
+	if i.pos+1 < i.Count() {
 		i.pos++
+		return true
 	}
-	return hasNext
+	i.pos = i.Count()
+	return false
 }
 
 func (i *MemoryIterator[T]) Prev() bool {
@@ -110,11 +163,18 @@ func (i *MemoryIterator[T]) Prev() bool {
 		return false
 	}
 
-	end := i.pos > 0
-	if !end {
+	if i.pos > 0 {
 		i.pos--
+		return true
 	}
-	return end
+	i.pos = -1
+	return false
+}
+
+func (i *MemoryIterator[T]) Prefix(prefix string) Iterator[T] {
+	return i.KeyFilter(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
 }
 
 func (i *MemoryIterator[T]) Release() {
@@ -127,13 +187,55 @@ func (i *MemoryIterator[T]) Release() {
 	}
 }
 
+func (i *MemoryIterator[T]) Reverse() Iterator[T] {
+	if i.released {
+		return i
+	}
+
+	k := make([]string, len(i.k))
+	for n, key := range i.k {
+		k[len(k)-1-n] = key
+	}
+
+	r := newMemoryIterator(i.m, k, i)
+	r.desc = !i.desc
+	return r
+}
+
+func (i *MemoryIterator[T]) Seek(key string) bool {
+	if i.released {
+		return false
+	}
+
+	for n, k := range i.k {
+		if (!i.desc && k >= key) || (i.desc && k <= key) {
+			i.pos = n
+			return true
+		}
+	}
+	i.pos = len(i.k)
+	return false
+}
+
+func (i *MemoryIterator[T]) Skip(n int) Iterator[T] {
+	if i.released {
+		return i
+	}
+
+	k := append([]string{}, i.k[min(max(n, 0), len(i.k)):]...)
+
+	s := newMemoryIterator(i.m, k, i)
+	s.desc = i.desc
+	return s
+}
+
 func (i *MemoryIterator[T]) Sort(f SortFunc[T]) Iterator[T] {
 	if i.released {
 		return i
 	}
 
 	s := &valueSort[T]{
-		a: makeSortable(i.m),
+		a: makeSortable(i.k, i.m),
 		f: f,
 	}
 	sort.Stable(s)
@@ -148,7 +250,7 @@ func (i *MemoryIterator[T]) SortKeys(f SortFunc[string]) Iterator[T] {
 	}
 
 	s := &keySort{
-		a: i.k,
+		a: append([]string{}, i.k...),
 		f: f,
 	}
 	sort.Stable(s)
@@ -157,11 +259,29 @@ func (i *MemoryIterator[T]) SortKeys(f SortFunc[string]) Iterator[T] {
 	return newMemoryIterator(i.m, k, i)
 }
 
+func (i *MemoryIterator[T]) TopK(n int, f SortFunc[T]) Iterator[T] {
+	if i.released {
+		return i
+	}
+
+	top, err := topK[T](i, n, f)
+	i.err = err
+	return top
+}
+
 func (i *MemoryIterator[T]) Value() (T, error) {
+	if i.released {
+		return i.m[""], ErrReleased
+	}
+
 	key := i.Key()
 	return i.m[key], nil
 }
 
+func (i *MemoryIterator[T]) Values() iter.Seq[T] {
+	return iterValues[T](i, &i.err)
+}
+
 func (i *MemoryIterator[T]) reset() {
 	i.pos = -1
 }
@@ -175,7 +295,7 @@ func newMemoryIterator[T any](m map[string]T, k []string, prev Iterator[T]) *Mem
 		prev: prev,
 	}
 
-	if len(k) > 0 {
+	if k != nil {
 		i.k = k
 	} else {
 		for k := range i.m {
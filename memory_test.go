@@ -1,6 +1,11 @@
 package ezdb
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
 
 func TestMemory(t *testing.T) {
 	c := Memory[*Student](nil)
@@ -12,3 +17,75 @@ func TestMemory(t *testing.T) {
 
 	fixture.Run()
 }
+
+func TestMemoryConcurrentReads(t *testing.T) {
+	c := Memory[*Student](nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	// Documents may be read while other goroutines write
+	wg := sync.WaitGroup{}
+	for n := 0; n < 10; n++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			if err := c.Put(fmt.Sprintf("student%d", n), &Student{Name: "Student"}); err != nil {
+				t.Errorf("failed to put student %d (%q)", n, err)
+			}
+		}(n)
+		go func(n int) {
+			defer wg.Done()
+			key := fmt.Sprintf("student%d", n)
+			c.Get(key)
+			c.Has(key)
+			iter := c.Iter()
+			iter.GetAll()
+			iter.Release()
+		}(n)
+	}
+	wg.Wait()
+
+	if n := c.Iter().Count(); n != 10 {
+		t.Errorf("incorrect count of students (expected 10, got %d)", n)
+	}
+}
+
+func TestMemoryIteratorBounds(t *testing.T) {
+	empty := newMemoryIterator(map[string]*Student{}, nil, nil)
+	if empty.First() || empty.Last() {
+		t.Error("expected empty iterator to have no first or last student")
+	}
+
+	i := newMemoryIterator(students, []string{"annie", "ben", "clive"}, nil)
+	if !i.Last() {
+		t.Fatal("expected iterator to have a last student")
+	}
+
+	// Moving past the end must stop, leaving no current key
+	for n := 0; n < 3; n++ {
+		if i.Next() {
+			t.Errorf("expected no student after the last (got '%s')", i.Key())
+		}
+		if key := i.Key(); key != "" {
+			t.Errorf("expected no key past the end (got '%s')", key)
+		}
+	}
+	if !i.Prev() || i.Key() != "clive" {
+		t.Errorf("expected to move back to the last student (got '%s')", i.Key())
+	}
+
+	// Moving before the start must stop rather than moving forward
+	i.First()
+	if i.Prev() {
+		t.Errorf("expected no student before the first (got '%s')", i.Key())
+	}
+	if !i.Next() || i.Key() != "annie" {
+		t.Errorf("expected to move forward to the first student (got '%s')", i.Key())
+	}
+
+	i.Release()
+	if _, err := i.Value(); !errors.Is(err, ErrReleased) {
+		t.Errorf("expected ErrReleased reading a released iterator (got %v)", err)
+	}
+}
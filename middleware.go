@@ -0,0 +1,222 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// Middleware intercepts operations on a collection.
+// Use Wrap to apply middleware to a collection.
+//
+// Each field is optional. If set, it is called in place of the operation, and should call next to continue to the next middleware or, eventually, the collection.
+// Middleware can modify arguments and results, or return early without calling next.
+//
+// Insert and Patch are implemented by the wrapped collection rather than the underlying one, so that the documents they write pass through Put middleware.
+// Insert generates a key with the underlying collection's ID generator and puts the document.
+// Patch gets the document, applies the patch and puts the result. Unlike Patch on the underlying collection, this is not atomic, so a concurrent write between the get and the put is overwritten.
+type Middleware[T any] struct {
+	Open   func(next func() error) error
+	Close  func(next func() error) error
+	Delete func(key string, next func(key string) error) error
+	Get    func(key string, next func(key string) (T, error)) (T, error)
+	Has    func(key string, next func(key string) (bool, error)) (bool, error)
+	Insert func(value T, next func(value T) (string, error)) (string, error)
+	Patch  func(key string, patch []byte, next func(key string, patch []byte) error) error
+	Put    func(key string, value T, next func(key string, value T) error) error
+	Iter   func(next func() Iterator[T]) Iterator[T]
+}
+
+// WrappedCollection is a collection with middleware.
+//
+// Only the operations of Middleware are available through a wrapped collection.
+// Constraints, hooks, validators and other settings must be configured on the underlying collection, and operations on the collection returned by Unwrap bypass middleware.
+type WrappedCollection[T any] struct {
+	c Collection[T]
+
+	open   func() error
+	close  func() error
+	delete func(key string) error
+	get    func(key string) (T, error)
+	has    func(key string) (bool, error)
+	insert func(value T) (string, error)
+	patch  func(key string, patch []byte) error
+	put    func(key string, value T) error
+	iter   func() Iterator[T]
+}
+
+func (c *WrappedCollection[T]) All() iter.Seq2[string, T] {
+	return collectionAll[T](c)
+}
+
+func (c *WrappedCollection[T]) Close() error {
+	return c.close()
+}
+
+func (c *WrappedCollection[T]) Delete(key string) error {
+	return c.delete(key)
+}
+
+func (c *WrappedCollection[T]) Get(key string) (T, error) {
+	return c.get(key)
+}
+
+func (c *WrappedCollection[T]) Has(key string) (bool, error) {
+	return c.has(key)
+}
+
+// Insert puts a document with a generated key, returning the key.
+// If the underlying collection does not have an ID generator, an error wrapping errors.ErrUnsupported is returned.
+func (c *WrappedCollection[T]) Insert(value T) (string, error) {
+	return c.insert(value)
+}
+
+func (c *WrappedCollection[T]) Iter() Iterator[T] {
+	return c.iter()
+}
+
+func (c *WrappedCollection[T]) Keys() iter.Seq[string] {
+	return collectionKeys[T](c)
+}
+
+func (c *WrappedCollection[T]) Open() error {
+	return c.open()
+}
+
+// Patch applies a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) to a document.
+// See Middleware for details.
+func (c *WrappedCollection[T]) Patch(key string, patch []byte) error {
+	return c.patch(key, patch)
+}
+
+func (c *WrappedCollection[T]) Put(key string, value T) error {
+	return c.put(key, value)
+}
+
+func (c *WrappedCollection[T]) Results() iter.Seq2[string, Result[T]] {
+	return collectionResults[T](c)
+}
+
+// Unwrap gets the underlying collection.
+// Operations on it are not intercepted by middleware.
+func (c *WrappedCollection[T]) Unwrap() Collection[T] {
+	return c.c
+}
+
+func (c *WrappedCollection[T]) Values() iter.Seq[T] {
+	return collectionValues[T](c)
+}
+
+// generator gets the ID generator of the underlying collection, so that wrapped collections can be wrapped again.
+func (c *WrappedCollection[T]) generator() IDGenerator {
+	if g, ok := c.c.(interface{ generator() IDGenerator }); ok {
+		return g.generator()
+	}
+	return nil
+}
+
+// Wrap applies middleware to a collection.
+// The first middleware is outermost, so it is called first and sees the final result.
+//
+// All, Keys, Results and Values are provided by Iter, so they are intercepted by Iter middleware.
+func Wrap[T any](c Collection[T], mws ...Middleware[T]) *WrappedCollection[T] {
+	w := &WrappedCollection[T]{
+		c: c,
+
+		open:   c.Open,
+		close:  c.Close,
+		delete: c.Delete,
+		get:    c.Get,
+		has:    c.Has,
+		put:    c.Put,
+		iter:   c.Iter,
+	}
+
+	// Insert and Patch call the outermost Get and Put, which are only complete once all middleware is applied
+	w.insert = func(value T) (string, error) {
+		g := w.generator()
+		if g == nil {
+			return "", fmt.Errorf("%w: %T does not have an ID generator", errors.ErrUnsupported, c)
+		}
+		return insert(g, c.Has, func(key string) error {
+			return w.put(key, value)
+		})
+	}
+	w.patch = func(key string, patch []byte) error {
+		value, err := w.get(key)
+		if err != nil {
+			return err
+		}
+
+		value, err = patchValue(value, patch, func() T {
+			var zero T
+			return zero
+		})
+		if err != nil {
+			return err
+		}
+
+		return w.put(key, value)
+	}
+
+	for n := len(mws) - 1; n >= 0; n-- {
+		mw := mws[n]
+
+		if mw.Open != nil {
+			next := w.open
+			w.open = func() error {
+				return mw.Open(next)
+			}
+		}
+		if mw.Close != nil {
+			next := w.close
+			w.close = func() error {
+				return mw.Close(next)
+			}
+		}
+		if mw.Delete != nil {
+			next := w.delete
+			w.delete = func(key string) error {
+				return mw.Delete(key, next)
+			}
+		}
+		if mw.Get != nil {
+			next := w.get
+			w.get = func(key string) (T, error) {
+				return mw.Get(key, next)
+			}
+		}
+		if mw.Has != nil {
+			next := w.has
+			w.has = func(key string) (bool, error) {
+				return mw.Has(key, next)
+			}
+		}
+		if mw.Insert != nil {
+			next := w.insert
+			w.insert = func(value T) (string, error) {
+				return mw.Insert(value, next)
+			}
+		}
+		if mw.Patch != nil {
+			next := w.patch
+			w.patch = func(key string, patch []byte) error {
+				return mw.Patch(key, patch, next)
+			}
+		}
+		if mw.Put != nil {
+			next := w.put
+			w.put = func(key string, value T) error {
+				return mw.Put(key, value, next)
+			}
+		}
+		if mw.Iter != nil {
+			next := w.iter
+			w.iter = func() Iterator[T] {
+				return mw.Iter(next)
+			}
+		}
+	}
+
+	return w
+}
@@ -0,0 +1,180 @@
+package ezdb
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	// Middleware with no interceptors must behave exactly like the collection
+	fixture := &CollectionTest{
+		C: Wrap(Memory[*Student](nil), Middleware[*Student]{}),
+		T: t,
+	}
+
+	fixture.Run()
+}
+
+func TestMiddleware(t *testing.T) {
+	calls := []string{}
+	logger := func(name string) Middleware[*Student] {
+		return Middleware[*Student]{
+			Open: func(next func() error) error {
+				calls = append(calls, name+":open")
+				return next()
+			},
+			Get: func(key string, next func(key string) (*Student, error)) (*Student, error) {
+				calls = append(calls, name+":get "+key)
+				return next(key)
+			},
+			Put: func(key string, value *Student, next func(key string, value *Student) error) error {
+				calls = append(calls, name+":put "+key)
+				return next(key, value)
+			},
+		}
+	}
+
+	errForbidden := errors.New("forbidden")
+	auth := Middleware[*Student]{
+		Put: func(key string, value *Student, next func(key string, value *Student) error) error {
+			if strings.HasPrefix(key, "admin") {
+				return errForbidden
+			}
+			return next(key, value)
+		},
+		Delete: func(key string, next func(key string) error) error {
+			return errForbidden
+		},
+	}
+
+	hidden := Middleware[*Student]{
+		Has: func(key string, next func(key string) (bool, error)) (bool, error) {
+			if key == "clive" {
+				return false, nil
+			}
+			return next(key)
+		},
+		Iter: func(next func() Iterator[*Student]) Iterator[*Student] {
+			return next().KeyFilter(func(key string) bool {
+				return key != "clive"
+			})
+		},
+	}
+
+	m := Memory[*Student](nil)
+	c := Wrap(m, logger("a"), auth, logger("b"), hidden)
+	if c.Unwrap() != m {
+		t.Error("expected Unwrap to return the underlying collection")
+	}
+
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	for _, key := range []string{"annie", "ben", "clive"} {
+		if err := c.Put(key, students[key]); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+	c.Get("annie")
+
+	if err := c.Put("admin", &Student{}); !errors.Is(err, errForbidden) {
+		t.Errorf("expected put to be forbidden (got %q)", err)
+	}
+	if err := c.Delete("annie"); !errors.Is(err, errForbidden) {
+		t.Errorf("expected delete to be forbidden (got %q)", err)
+	}
+	if has, _ := m.Has("admin"); has {
+		t.Error("expected forbidden student not to be stored")
+	}
+
+	expected := []string{
+		"a:open", "b:open",
+		"a:put annie", "b:put annie",
+		"a:put ben", "b:put ben",
+		"a:put clive", "b:put clive",
+		"a:get annie", "b:get annie",
+		"a:put admin",
+	}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("incorrect middleware calls (expected %v, got %v)", expected, calls)
+	}
+
+	// Insert and Patch write through Put middleware
+	m.SetIDGenerator(&repeatGenerator{ids: []string{"admin1"}})
+	if _, err := c.Insert(&Student{}); !errors.Is(err, errForbidden) {
+		t.Errorf("expected insert to be forbidden (got %q)", err)
+	}
+	if err := m.Put("admin", &Student{Age: 40}); err != nil {
+		t.Fatalf("failed to put student 'admin' (%q)", err)
+	}
+	if err := c.Patch("admin", []byte(`{"age":41}`)); !errors.Is(err, errForbidden) {
+		t.Errorf("expected patch to be forbidden (got %q)", err)
+	}
+	if value, _ := m.Get("admin"); value.Age != 40 {
+		t.Errorf("expected forbidden patch not to be stored (got age %d)", value.Age)
+	}
+	m.Delete("admin")
+
+	if has, _ := c.Has("clive"); has {
+		t.Error("expected clive to be hidden by Has middleware")
+	}
+	if keys := slices.Collect(c.Keys()); len(keys) != 2 || slices.Contains(keys, "clive") {
+		t.Errorf("expected clive to be hidden by Iter middleware (got %v)", keys)
+	}
+}
+
+func TestMiddlewareInsertPatch(t *testing.T) {
+	calls := []string{}
+	logger := Middleware[*Student]{
+		Insert: func(value *Student, next func(value *Student) (string, error)) (string, error) {
+			key, err := next(value)
+			calls = append(calls, "insert "+key)
+			return key, err
+		},
+		Patch: func(key string, patch []byte, next func(key string, patch []byte) error) error {
+			calls = append(calls, "patch "+key)
+			return next(key, patch)
+		},
+	}
+
+	m := Memory[*Student](nil).SetIDGenerator(&repeatGenerator{ids: []string{"a"}})
+	c := Wrap(m, logger)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	if _, err := c.Insert(&Student{Name: "Annie", Age: 32}); err != nil {
+		t.Errorf("failed to insert student (%q)", err)
+	}
+	if err := c.Patch("a", []byte(`{"age":33}`)); err != nil {
+		t.Errorf("failed to patch student (%q)", err)
+	}
+	if value, err := m.Get("a"); err != nil || value.Age != 33 {
+		t.Errorf("expected student to be patched (got %v, %q)", value, err)
+	}
+
+	expected := []string{"insert a", "patch a"}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("incorrect middleware calls (expected %v, got %v)", expected, calls)
+	}
+
+	// Insert is not supported without an ID generator, but Patch only needs Get and Put
+	w := Wrap[*Student](struct{ Collection[*Student] }{m})
+	if _, err := w.Insert(&Student{}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for insert (got %q)", err)
+	}
+	if err := w.Patch("a", []byte(`{"age":34}`)); err != nil {
+		t.Errorf("failed to patch student without Patch (%q)", err)
+	}
+	if value, err := m.Get("a"); err != nil || value.Age != 34 {
+		t.Errorf("expected student to be patched without Patch (got %v, %q)", value, err)
+	}
+
+	// Wrapped collections can be wrapped again
+	m.SetIDGenerator(&repeatGenerator{ids: []string{"b"}})
+	if key, err := Wrap[*Student](c).Insert(&Student{Name: "Ben"}); err != nil || key != "b" {
+		t.Errorf("failed to insert student into rewrapped collection (got %s, %q)", key, err)
+	}
+}
@@ -0,0 +1,25 @@
+package ezdb
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// MsgPackMarshaler is a DocumentMarshaler that converts documents to MessagePack data.
+type MsgPackMarshaler[T any] struct {
+	factory func() T
+}
+
+func (m *MsgPackMarshaler[T]) Factory() T {
+	return m.factory()
+}
+
+func (m *MsgPackMarshaler[T]) Marshal(src T) ([]byte, error) {
+	return msgpack.Marshal(src)
+}
+
+func (m *MsgPackMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	return msgpack.Unmarshal(src, dest)
+}
+
+// MsgPack creates a DocumentMarshaler that converts documents to MessagePack data.
+func MsgPack[T any](factory func() T) *MsgPackMarshaler[T] {
+	return &MsgPackMarshaler[T]{factory: factory}
+}
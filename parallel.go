@@ -0,0 +1,171 @@
+package ezdb
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// ParallelOptions configures ParallelFilter and ParallelForEach.
+type ParallelOptions struct {
+	// Workers is the number of documents processed at once.
+	// If zero or less, one worker is used per CPU.
+	Workers int
+
+	// Unordered allows ParallelFilter to return documents in the order they are processed, rather than the order of the iterator.
+	// This avoids sorting documents once they have all been processed.
+	Unordered bool
+}
+
+// parallelJob is a document to be processed by a worker.
+type parallelJob[T any] struct {
+	seq  int
+	key  string
+	load func() (T, error)
+}
+
+// ParallelFilter creates a new iterator with a subset of documents, evaluating f for several documents at once.
+// The order of documents is preserved unless o.Unordered is set. The previous iterator will not be affected.
+//
+// See ParallelForEach for details of how documents are processed.
+func ParallelFilter[T any](i Iterator[T], o *ParallelOptions, f FilterFunc[T]) (Iterator[T], error) {
+	type match struct {
+		seq   int
+		key   string
+		value T
+	}
+
+	matches := []match{}
+	mx := sync.Mutex{}
+
+	err := parallel(i, o.GetWorkers(), func(seq int, key string, value T) error {
+		if f(key, value) {
+			mx.Lock()
+			matches = append(matches, match{seq: seq, key: key, value: value})
+			mx.Unlock()
+		}
+		return nil
+	})
+	if err != nil {
+		return newMemoryIterator(map[string]T{}, []string{}, i), err
+	}
+
+	if !o.GetUnordered() {
+		sort.Slice(matches, func(a, b int) bool {
+			return matches[a].seq < matches[b].seq
+		})
+	}
+
+	k := make([]string, 0, len(matches))
+	m := make(map[string]T, len(matches))
+	for _, match := range matches {
+		k = append(k, match.key)
+		m[match.key] = match.value
+	}
+	return newMemoryIterator(m, k, i), nil
+}
+
+// ParallelForEach calls f for every document in an iterator, using several workers at once.
+// If o is nil, one worker is used per CPU.
+//
+// Documents are read by a single goroutine, but for LevelDB iterators, unmarshaling is performed by workers.
+// Documents are not processed in any particular order.
+//
+// If a document cannot be read or f returns an error, no further documents are processed and the first error is returned.
+func ParallelForEach[T any](i Iterator[T], o *ParallelOptions, f func(key string, value T) error) error {
+	return parallel(i, o.GetWorkers(), func(seq int, key string, value T) error {
+		return f(key, value)
+	})
+}
+
+func (o *ParallelOptions) GetUnordered() bool {
+	if o == nil {
+		return false
+	}
+	return o.Unordered
+}
+
+func (o *ParallelOptions) GetWorkers() int {
+	if o == nil {
+		return 0
+	}
+	return o.Workers
+}
+
+func parallel[T any](i Iterator[T], workers int, f func(seq int, key string, value T) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	jobs := make(chan parallelJob[T], workers)
+	done := make(chan struct{})
+
+	var failed error
+	once := sync.Once{}
+	fail := func(err error) {
+		once.Do(func() {
+			failed = err
+			close(done)
+		})
+	}
+
+	wg := sync.WaitGroup{}
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				select {
+				case <-done:
+					continue
+				default:
+				}
+
+				value, err := job.load()
+				if err != nil {
+					fail(&DocumentError{Key: job.key, Err: err})
+					continue
+				}
+				if err := f(job.seq, job.key, value); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	seq := 0
+produce:
+	for ok := i.First(); ok; ok = i.Next() {
+		job := parallelJob[T]{seq: seq, key: i.Key(), load: deferValue(i)}
+		seq++
+
+		select {
+		case jobs <- job:
+		case <-done:
+			break produce
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failed != nil {
+		return failed
+	}
+	return i.Err()
+}
+
+// valueDeferrer is implemented by iterators that can defer reading the current value, such as by deferring unmarshaling.
+type valueDeferrer[T any] interface {
+	// deferValue gets a function that reads the current value, and can be called on another goroutine after the iterator has moved.
+	deferValue() func() (T, error)
+}
+
+// deferValue gets a function that reads the current value of an iterator, and can be called after the iterator has moved.
+// Iterators that implement valueDeferrer, such as LevelDB iterators, may defer work to the function so that it can be performed on another goroutine.
+func deferValue[T any](i Iterator[T]) func() (T, error) {
+	if d, ok := i.(valueDeferrer[T]); ok {
+		return d.deferValue()
+	}
+
+	return streamValue(i.Value())
+}
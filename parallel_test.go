@@ -0,0 +1,68 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	path := ".leveldb/leveldb_parallel_test"
+	c := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	total := 200
+	expected := 0
+	for n := 0; n < total; n++ {
+		key := fmt.Sprintf("student-%03d", n)
+		age := (n * 37) % 100
+		expected += age
+		if err := c.Put(key, &Student{Name: key, Age: age}); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	var sum int64
+	err := ParallelForEach(c.Iter(), &ParallelOptions{Workers: 4}, func(key string, value *Student) error {
+		atomic.AddInt64(&sum, int64(value.Age))
+		return nil
+	})
+	if err != nil {
+		t.Errorf("failed to iterate students (%q)", err)
+	} else if int(sum) != expected {
+		t.Errorf("incorrect sum of ages (expected %d, got %d)", expected, sum)
+	}
+
+	over50 := func(key string, value *Student) bool {
+		return value.Age > 50
+	}
+	want := c.Iter().Filter(over50).GetAllKeys()
+	iter, err := ParallelFilter(c.Iter(), &ParallelOptions{Workers: 4}, over50)
+	if err != nil {
+		t.Errorf("failed to filter students (%q)", err)
+	} else if got := iter.GetAllKeys(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("incorrect filtered students (expected %v, got %v)", want, got)
+	}
+
+	iter, err = ParallelFilter(c.Iter(), &ParallelOptions{Workers: 4, Unordered: true}, over50)
+	if err != nil {
+		t.Errorf("failed to filter students unordered (%q)", err)
+	} else if got := iter.GetAllKeys(); len(got) != len(want) {
+		t.Errorf("incorrect count of unordered filtered students (expected %d, got %d)", len(want), len(got))
+	}
+
+	errStop := errors.New("stop")
+	err = ParallelForEach(c.Iter(), nil, func(key string, value *Student) error {
+		if key == "student-100" {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected error from ParallelForEach (got %q)", err)
+	}
+}
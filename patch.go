@@ -0,0 +1,386 @@
+package ezdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ApplyPatch applies a patch to a JSON document.
+//
+// If the patch is a JSON array, it is applied as a JSON Patch (RFC 6902).
+// If the patch is a JSON object, it is applied as a JSON Merge Patch (RFC 7386).
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	var target any
+	if err := jsonDecode(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var p any
+	if err := jsonDecode(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	switch p := p.(type) {
+	case []any:
+		ops := []jsonPatchOp{}
+		if err := jsonDecode(patch, &ops); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		for n, op := range ops {
+			var err error
+			if target, err = op.apply(target); err != nil {
+				return nil, fmt.Errorf("%w: operation %d (%s %s): %v", ErrInvalidPatch, n, op.Op, op.Path, err)
+			}
+		}
+	case map[string]any:
+		target = mergePatch(target, p)
+	default:
+		return nil, fmt.Errorf("%w: patch must be an array or object", ErrInvalidPatch)
+	}
+
+	return json.Marshal(target)
+}
+
+// jsonPatchOp is an operation in a JSON Patch.
+type jsonPatchOp struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+func (op jsonPatchOp) apply(doc any) (any, error) {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		var value any
+		if err := jsonDecode(*op.Value, &value); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return jsonPointerAdd(doc, path, value)
+		case "replace":
+			if _, err := jsonPointerGet(doc, path); err != nil {
+				return nil, err
+			}
+			if doc, _, err = jsonPointerRemove(doc, path); err != nil {
+				return nil, err
+			}
+			return jsonPointerAdd(doc, path, value)
+		default:
+			current, err := jsonPointerGet(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !jsonEqual(current, value) {
+				return nil, fmt.Errorf("test failed")
+			}
+			return doc, nil
+		}
+
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if op.Op == "move" {
+			if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+				return nil, fmt.Errorf("cannot move a value into itself")
+			}
+			if doc, value, err = jsonPointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			if value, err = jsonPointerGet(doc, from); err != nil {
+				return nil, err
+			}
+			value = jsonCopy(value)
+		}
+		return jsonPointerAdd(doc, path, value)
+	}
+
+	return nil, fmt.Errorf("unknown operation")
+}
+
+// jsonCopy deep copies a decoded JSON value.
+func jsonCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, value := range v {
+			c[key] = jsonCopy(value)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for n, value := range v {
+			c[n] = jsonCopy(value)
+		}
+		return c
+	}
+	return v
+}
+
+// jsonDecode decodes JSON, preserving numbers as json.Number so that they are not changed by a round trip.
+func jsonDecode(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// jsonEqual compares decoded JSON values as required by the test operation of a JSON Patch.
+// Numbers are equal if their values are equal, such as 1 and 1.0, and objects are equal regardless of the order of their members.
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+		if !okX || !okY {
+			return a == b
+		}
+		return x.Cmp(y) == 0
+
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for n := range a {
+			if !jsonEqual(a[n], b[n]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return a == b
+}
+
+// jsonIndex parses an array index in a JSON pointer.
+// If end is true, "-" refers to the end of the array.
+func jsonIndex(token string, n int, end bool) (int, error) {
+	if end && token == "-" {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	max := n - 1
+	if end {
+		max = n
+	}
+	if index > max {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// jsonPointerAdd adds a value to a document, returning the updated document.
+func jsonPointerAdd(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[last] = value
+		return doc, nil
+	case []any:
+		index, err := jsonIndex(last, len(p), true)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, nil)
+		copy(p[index+1:], p[index:])
+		p[index] = value
+		return jsonPointerSet(doc, path[:len(path)-1], p)
+	}
+	return nil, fmt.Errorf("cannot add to a scalar value")
+}
+
+// jsonPointerGet gets the value at a path in a document.
+func jsonPointerGet(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch d := doc.(type) {
+		case map[string]any:
+			value, ok := d[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			doc = value
+		case []any:
+			index, err := jsonIndex(token, len(d), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = d[index]
+		default:
+			return nil, fmt.Errorf("member %q not found", token)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerRemove removes the value at a path in a document, returning the updated document and the removed value.
+func jsonPointerRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		value, ok := p[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q not found", last)
+		}
+		delete(p, last)
+		return doc, value, nil
+	case []any:
+		index, err := jsonIndex(last, len(p), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := p[index]
+		p = append(p[:index:index], p[index+1:]...)
+		doc, err = jsonPointerSet(doc, path[:len(path)-1], p)
+		return doc, value, err
+	}
+	return nil, nil, fmt.Errorf("member %q not found", last)
+}
+
+// jsonPointerSet replaces the value at an existing path in a document, returning the updated document.
+func jsonPointerSet(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[last] = value
+	case []any:
+		index, err := jsonIndex(last, len(p), false)
+		if err != nil {
+			return nil, err
+		}
+		p[index] = value
+	}
+	return doc, nil
+}
+
+// mergePatch applies a JSON Merge Patch to a decoded document.
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+// parseJSONPointer parses a JSON Pointer (RFC 6901) into its reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for n, token := range tokens {
+		tokens[n] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// patchValue applies a patch to a document by way of its JSON representation.
+func patchValue[T any](value T, patch []byte, factory func() T) (T, error) {
+	dest := factory()
+
+	doc, err := json.Marshal(value)
+	if err != nil {
+		return dest, err
+	}
+
+	doc, err = ApplyPatch(doc, patch)
+	if err != nil {
+		return dest, err
+	}
+
+	err = json.Unmarshal(doc, &dest)
+	return dest, err
+}
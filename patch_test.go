@@ -0,0 +1,122 @@
+package ezdb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	type patchCase struct {
+		doc      string
+		patch    string
+		expected string
+	}
+
+	cases := []patchCase{
+		// JSON Merge Patch (RFC 7386, appendix A)
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`{"a":12345678901234567890}`, `{"b":{"c":null}}`, `{"a":12345678901234567890,"b":{}}`},
+
+		// JSON Patch (RFC 6902, appendix A)
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"foo":{"a":1}}`, `[{"op":"copy","from":"/foo","path":"/bar"},{"op":"add","path":"/bar/b","value":2}]`, `{"bar":{"a":1,"b":2},"foo":{"a":1}}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"a":1,"b":{"c":[1.5,"x"],"d":null}}`, `[{"op":"test","path":"/a","value":1.0},{"op":"test","path":"/a","value":1e0},{"op":"test","path":"/b","value":{"d":null,"c":[15e-1,"x"]}}]`, `{"a":1,"b":{"c":[1.5,"x"],"d":null}}`},
+		{`{"/":1,"~":2}`, `[{"op":"replace","path":"/~1","value":3},{"op":"remove","path":"/~0"}]`, `{"/":3}`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+	}
+
+	for _, c := range cases {
+		actual, err := ApplyPatch([]byte(c.doc), []byte(c.patch))
+		if err != nil {
+			t.Errorf("failed to apply %s to %s (%q)", c.patch, c.doc, err)
+		} else if string(actual) != c.expected {
+			t.Errorf("incorrect result of %s applied to %s (expected %s, got %s)", c.patch, c.doc, c.expected, actual)
+		}
+	}
+
+	invalid := []patchCase{
+		{doc: `{"foo":"bar"}`, patch: `"foo"`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"remove","path":"/baz"}]`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"replace","path":"/baz","value":1}]`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"test","path":"/foo","value":"baz"}]`},
+		{doc: `{"foo":1}`, patch: `[{"op":"test","path":"/foo","value":"1"}]`},
+		{doc: `{"foo":{"a":1}}`, patch: `[{"op":"test","path":"/foo","value":{"a":1,"b":2}}]`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/foo/bar/baz","value":1}]`},
+		{doc: `{"foo":[1]}`, patch: `[{"op":"add","path":"/foo/2","value":1}]`},
+		{doc: `{"foo":[1]}`, patch: `[{"op":"add","path":"/foo/01","value":1}]`},
+		{doc: `{"foo":{"a":1}}`, patch: `[{"op":"move","from":"/foo","path":"/foo/a/b"}]`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"add","path":"/baz"}]`},
+		{doc: `{"foo":"bar"}`, patch: `[{"op":"frobnicate","path":"/foo"}]`},
+	}
+
+	for _, c := range invalid {
+		if _, err := ApplyPatch([]byte(c.doc), []byte(c.patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("expected ErrInvalidPatch applying %s to %s (got %q)", c.patch, c.doc, err)
+		}
+	}
+}
+
+func TestPatch(t *testing.T) {
+	path := ".leveldb/leveldb_patch_test"
+	l := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := l.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer l.Destroy()
+
+	m := Memory[*Student](nil)
+	if err := m.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	collections := map[string]interface {
+		Collection[*Student]
+		Patch(key string, patch []byte) error
+	}{
+		"leveldb": l,
+		"memory":  m,
+	}
+
+	for name, c := range collections {
+		annie := &Student{Name: "Annie", Age: 32}
+		if err := c.Put("annie", annie); err != nil {
+			t.Fatalf("(%s) failed to put student (%q)", name, err)
+		}
+
+		if err := c.Patch("annie", []byte(`{"age":33}`)); err != nil {
+			t.Errorf("(%s) failed to merge patch student (%q)", name, err)
+		}
+		if err := c.Patch("annie", []byte(`[{"op":"test","path":"/age","value":33},{"op":"replace","path":"/name","value":"Ann"}]`)); err != nil {
+			t.Errorf("(%s) failed to patch student (%q)", name, err)
+		}
+		if err := c.Patch("annie", []byte(`[{"op":"test","path":"/age","value":32}]`)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("(%s) expected ErrInvalidPatch for failed test (got %q)", name, err)
+		}
+		if err := c.Patch(nonexistentStudentKey, []byte(`{"age":1}`)); err == nil {
+			t.Errorf("(%s) expected error patching nonexistent student", name)
+		}
+
+		if value, err := c.Get("annie"); err != nil {
+			t.Errorf("(%s) failed to get student (%q)", name, err)
+		} else if value.Name != "Ann" || value.Age != 33 {
+			t.Errorf("(%s) incorrect patched student (expected Ann aged 33, got %s aged %d)", name, value.Name, value.Age)
+		}
+
+		if annie.Name != "Annie" || annie.Age != 32 {
+			t.Errorf("(%s) expected original student not to be modified", name)
+		}
+	}
+}
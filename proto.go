@@ -0,0 +1,62 @@
+package ezdb
+
+import "google.golang.org/protobuf/proto"
+
+// ProtoMarshaler is a DocumentMarshaler that converts documents to Protocol Buffers wire format.
+type ProtoMarshaler[T proto.Message] struct {
+	factory func() T
+
+	optMarshal   proto.MarshalOptions
+	optUnmarshal proto.UnmarshalOptions
+}
+
+// ProtoOptions configures a ProtoMarshaler.
+type ProtoOptions struct {
+	Marshal   proto.MarshalOptions
+	Unmarshal proto.UnmarshalOptions
+}
+
+func (m *ProtoMarshaler[T]) Factory() T {
+	if m.factory != nil {
+		return m.factory()
+	}
+
+	// Generated message types support ProtoReflect on a nil pointer, so the zero value is enough to create a new message
+	var zero T
+	return zero.ProtoReflect().Type().New().Interface().(T)
+}
+
+func (m *ProtoMarshaler[T]) Marshal(src T) ([]byte, error) {
+	return m.optMarshal.Marshal(src)
+}
+
+func (m *ProtoMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	return m.optUnmarshal.Unmarshal(src, dest)
+}
+
+func (o *ProtoOptions) GetMarshal() proto.MarshalOptions {
+	if o == nil {
+		return proto.MarshalOptions{}
+	}
+	return o.Marshal
+}
+
+func (o *ProtoOptions) GetUnmarshal() proto.UnmarshalOptions {
+	if o == nil {
+		return proto.UnmarshalOptions{}
+	}
+	return o.Unmarshal
+}
+
+// Proto creates a DocumentMarshaler that converts documents to Protocol Buffers wire format.
+//
+// If factory is nil, new documents are created through protobuf reflection.
+// Set Marshal.Deterministic in the options if stored values must be byte-for-byte reproducible.
+func Proto[T proto.Message](factory func() T, o *ProtoOptions) *ProtoMarshaler[T] {
+	return &ProtoMarshaler[T]{
+		factory: factory,
+
+		optMarshal:   o.GetMarshal(),
+		optUnmarshal: o.GetUnmarshal(),
+	}
+}
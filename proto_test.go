@@ -0,0 +1,60 @@
+package ezdb
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProtoFactory(t *testing.T) {
+	m := Proto[*structpb.Struct](nil, nil)
+
+	t.Logf("creating empty struct")
+	value := m.Factory()
+	if value == nil {
+		t.Error("factory did not create a message")
+	}
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	m := Proto[*structpb.Struct](nil, &ProtoOptions{
+		Marshal: proto.MarshalOptions{Deterministic: true},
+	})
+
+	for key, student := range students {
+		src, err := structpb.NewStruct(map[string]any{"name": student.Name, "age": student.Age})
+		if err != nil {
+			t.Fatalf("failed to create struct for student '%s' (%q)", key, err)
+		}
+
+		t.Logf("marshaling student '%s'", key)
+		b, err := m.Marshal(src)
+		if err != nil {
+			t.Errorf("failed to marshal student '%s' (%q)", key, err)
+			continue
+		}
+
+		// Deterministic marshaling must produce identical output for equal messages
+		again, err := m.Marshal(src)
+		if err != nil {
+			t.Errorf("failed to marshal student '%s' again (%q)", key, err)
+		} else if string(again) != string(b) {
+			t.Errorf("student '%s' marshaled non-deterministically", key)
+		}
+
+		dest := m.Factory()
+		if err := m.Unmarshal(b, dest); err != nil {
+			t.Errorf("failed to unmarshal student '%s' (%q)", key, err)
+			continue
+		}
+
+		actual := &Student{
+			Name: dest.Fields["name"].GetStringValue(),
+			Age:  int(dest.Fields["age"].GetNumberValue()),
+		}
+		if err := compareStudent(key, student, actual); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,613 @@
+package ezdb
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// QueryKey is the field name used to refer to the key of a document in a query.
+const QueryKey = "@key"
+
+// Query is a parsed query expression.
+// Use ParseQuery to create a Query.
+//
+// A query consists of comparisons joined by AND, OR and NOT, and grouped by parentheses. For example:
+//
+//	age > 30 AND (name ^= "A" OR NOT address.city = "London")
+//
+// Each comparison consists of a field, an operator and a value.
+// A field may be the name of a struct field, its JSON name, or a map key, and nested fields are separated by dots.
+// The key of a document is referred to as @key.
+//
+// The supported operators are = (or ==), !=, >, >=, <, <=, as well as ^= (starts with), $= (ends with) and *= (contains) which apply only to strings.
+// Values may be double-quoted strings, numbers, true, false or null.
+// A comparison with a value of a different type, or with a missing field, does not match, except with the != operator.
+type Query[T any] struct {
+	expr queryExpr
+}
+
+type queryExpr interface {
+	keyOnly() bool
+	match(key string, doc reflect.Value) bool
+}
+
+type queryAnd struct {
+	exprs []queryExpr
+}
+
+type queryCompare struct {
+	path  []string
+	op    string
+	value any
+}
+
+type queryNot struct {
+	expr queryExpr
+}
+
+type queryOr struct {
+	exprs []queryExpr
+}
+
+type queryToken struct {
+	kind string
+	text string
+	pos  int
+}
+
+// Query token kinds.
+const (
+	queryEOF    = "end of query"
+	queryIdent  = "field"
+	queryNumber = "number"
+	queryOp     = "operator"
+	queryParen  = "parenthesis"
+	queryString = "string"
+)
+
+type queryParser struct {
+	t      reflect.Type
+	tokens []queryToken
+	pos    int
+}
+
+// ParseQuery parses a query expression for documents of type T.
+// If T is a struct, or a pointer to one, fields are checked when the query is parsed.
+func ParseQuery[T any](q string) (*Query[T], error) {
+	tokens, err := lexQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{t: reflect.TypeOf((*T)(nil)).Elem(), tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != queryEOF {
+		return nil, p.unexpected(tok)
+	}
+	return &Query[T]{expr: expr}, nil
+}
+
+// Apply a query to an iterator, creating a new iterator with a subset of documents.
+// The previous iterator will not be affected.
+//
+// Comparisons with the document key that must be true for any document to match are applied with Prefix or KeyFilter, so that other documents are not unmarshaled.
+func (q *Query[T]) Apply(i Iterator[T]) Iterator[T] {
+	exprs := []queryExpr{q.expr}
+	if and, ok := q.expr.(*queryAnd); ok {
+		exprs = and.exprs
+	}
+
+	rest := []queryExpr{}
+	for _, expr := range exprs {
+		if !expr.keyOnly() {
+			rest = append(rest, expr)
+			continue
+		}
+
+		if cmp, ok := expr.(*queryCompare); ok {
+			if prefix, ok := cmp.value.(string); ok && (cmp.op == "=" || cmp.op == "^=") {
+				i = i.Prefix(prefix)
+			}
+		}
+		keyExpr := expr
+		i = i.KeyFilter(func(key string) bool {
+			return keyExpr.match(key, reflect.Value{})
+		})
+	}
+
+	if len(rest) > 0 {
+		expr := &queryAnd{exprs: rest}
+		i = i.Filter(func(key string, value T) bool {
+			return expr.match(key, reflect.ValueOf(value))
+		})
+	}
+	return i
+}
+
+// Filter gets a FilterFunc that matches documents using the query.
+func (q *Query[T]) Filter() FilterFunc[T] {
+	return func(key string, value T) bool {
+		return q.expr.match(key, reflect.ValueOf(value))
+	}
+}
+
+func (e *queryAnd) keyOnly() bool {
+	for _, expr := range e.exprs {
+		if !expr.keyOnly() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *queryAnd) match(key string, doc reflect.Value) bool {
+	for _, expr := range e.exprs {
+		if !expr.match(key, doc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *queryCompare) keyOnly() bool {
+	return len(e.path) == 1 && e.path[0] == QueryKey
+}
+
+func (e *queryCompare) match(key string, doc reflect.Value) bool {
+	var field reflect.Value
+	if e.keyOnly() {
+		field = reflect.ValueOf(key)
+	} else {
+		field = queryField(doc, e.path)
+	}
+
+	if e.value == nil {
+		isNull := !field.IsValid()
+		if field.IsValid() {
+			switch field.Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+				isNull = field.IsNil()
+			}
+		}
+		return isNull == (e.op == "=")
+	}
+
+	n, ok := queryCompareValue(field, e.value, e.op)
+	if !ok {
+		return e.op == "!="
+	}
+
+	switch e.op {
+	case "=", "^=", "$=", "*=":
+		return n == 0
+	case "!=":
+		return n != 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	}
+	return false
+}
+
+func (e *queryNot) keyOnly() bool {
+	return e.expr.keyOnly()
+}
+
+func (e *queryNot) match(key string, doc reflect.Value) bool {
+	return !e.expr.match(key, doc)
+}
+
+func (e *queryOr) keyOnly() bool {
+	for _, expr := range e.exprs {
+		if !expr.keyOnly() {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *queryOr) match(key string, doc reflect.Value) bool {
+	for _, expr := range e.exprs {
+		if expr.match(key, doc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != queryEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	exprs := []queryExpr{}
+	for {
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if !p.peekKeyword("AND") {
+			break
+		}
+		p.next()
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &queryAnd{exprs: exprs}, nil
+}
+
+func (p *queryParser) parseCompare() (queryExpr, error) {
+	tok := p.next()
+	if tok.kind != queryIdent || isQueryKeyword(tok.text) {
+		return nil, p.unexpected(tok)
+	}
+	path := strings.Split(tok.text, ".")
+	if err := p.checkPath(path, tok); err != nil {
+		return nil, err
+	}
+
+	opTok := p.next()
+	if opTok.kind != queryOp {
+		return nil, p.unexpected(opTok)
+	}
+	op := opTok.text
+	if op == "==" {
+		op = "="
+	}
+
+	valueTok := p.next()
+	var value any
+	switch {
+	case valueTok.kind == queryString:
+		s, err := strconv.Unquote(valueTok.text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid string %s at position %d", ErrInvalidQuery, valueTok.text, valueTok.pos)
+		}
+		value = s
+	case valueTok.kind == queryNumber:
+		n, err := strconv.ParseFloat(valueTok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %s at position %d", ErrInvalidQuery, valueTok.text, valueTok.pos)
+		}
+		value = n
+	case valueTok.kind == queryIdent && strings.EqualFold(valueTok.text, "true"):
+		value = true
+	case valueTok.kind == queryIdent && strings.EqualFold(valueTok.text, "false"):
+		value = false
+	case valueTok.kind == queryIdent && strings.EqualFold(valueTok.text, "null"):
+		value = nil
+	default:
+		return nil, p.unexpected(valueTok)
+	}
+
+	if _, ok := value.(string); !ok {
+		if op == "^=" || op == "$=" || op == "*=" {
+			return nil, fmt.Errorf("%w: operator %s requires a string at position %d", ErrInvalidQuery, op, valueTok.pos)
+		}
+	}
+	if value == nil && op != "=" && op != "!=" {
+		return nil, fmt.Errorf("%w: operator %s cannot be used with null at position %d", ErrInvalidQuery, op, valueTok.pos)
+	}
+
+	return &queryCompare{path: path, op: op, value: value}, nil
+}
+
+func (p *queryParser) parseNot() (queryExpr, error) {
+	if p.peekKeyword("NOT") {
+		p.next()
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNot{expr: expr}, nil
+	}
+
+	if tok := p.peek(); tok.kind == queryParen && tok.text == "(" {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != queryParen || tok.text != ")" {
+			return nil, p.unexpected(tok)
+		}
+		return expr, nil
+	}
+
+	return p.parseCompare()
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	exprs := []queryExpr{}
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if !p.peekKeyword("OR") {
+			break
+		}
+		p.next()
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return &queryOr{exprs: exprs}, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) peekKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == queryIdent && strings.EqualFold(tok.text, keyword)
+}
+
+// checkPath checks that a field exists, as far as it can be determined from the document type.
+func (p *queryParser) checkPath(path []string, tok queryToken) error {
+	if len(path) == 1 && path[0] == QueryKey {
+		return nil
+	}
+
+	t := p.t
+	for _, name := range path {
+		if name == "" {
+			return fmt.Errorf("%w: invalid field %s at position %d", ErrInvalidQuery, tok.text, tok.pos)
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		f, ok := queryStructField(t, name)
+		if !ok {
+			return fmt.Errorf("%w: unknown field %s at position %d", ErrInvalidQuery, tok.text, tok.pos)
+		}
+		t = f.Type
+	}
+	return nil
+}
+
+func (p *queryParser) unexpected(tok queryToken) error {
+	if tok.kind == queryEOF {
+		return fmt.Errorf("%w: unexpected end of query", ErrInvalidQuery)
+	}
+	return fmt.Errorf("%w: unexpected %s %s at position %d", ErrInvalidQuery, tok.kind, tok.text, tok.pos)
+}
+
+func isQueryKeyword(s string) bool {
+	for _, keyword := range []string{"AND", "OR", "NOT", "true", "false", "null"} {
+		if strings.EqualFold(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// lexQuery splits a query into tokens.
+func lexQuery(q string) ([]queryToken, error) {
+	tokens := []queryToken{}
+
+	for pos := 0; pos < len(q); {
+		c, width := utf8.DecodeRuneInString(q[pos:])
+		start := pos
+
+		switch {
+		case unicode.IsSpace(c):
+			pos += width
+			continue
+
+		case c == '(' || c == ')':
+			pos++
+			tokens = append(tokens, queryToken{kind: queryParen, text: q[start:pos], pos: start})
+
+		case c == '"':
+			pos++
+			for pos < len(q) && q[pos] != '"' {
+				if q[pos] == '\\' {
+					pos++
+				}
+				pos++
+			}
+			if pos >= len(q) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidQuery, start)
+			}
+			pos++
+			tokens = append(tokens, queryToken{kind: queryString, text: q[start:pos], pos: start})
+
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			pos++
+			for pos < len(q) && strings.ContainsRune("0123456789.eE+-", rune(q[pos])) {
+				if (q[pos] == '+' || q[pos] == '-') && q[pos-1] != 'e' && q[pos-1] != 'E' {
+					break
+				}
+				pos++
+			}
+			tokens = append(tokens, queryToken{kind: queryNumber, text: q[start:pos], pos: start})
+
+		case c == '@' || c == '_' || unicode.IsLetter(c):
+			pos += width
+			for pos < len(q) {
+				c, width := utf8.DecodeRuneInString(q[pos:])
+				if c != '_' && c != '.' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+				pos += width
+			}
+			tokens = append(tokens, queryToken{kind: queryIdent, text: q[start:pos], pos: start})
+
+		case strings.ContainsRune("=!<>^$*", c):
+			pos++
+			if pos < len(q) && q[pos] == '=' {
+				pos++
+			}
+			op := q[start:pos]
+			switch op {
+			case "=", "==", "!=", ">", ">=", "<", "<=", "^=", "$=", "*=":
+				tokens = append(tokens, queryToken{kind: queryOp, text: op, pos: start})
+			default:
+				return nil, fmt.Errorf("%w: invalid operator %s at position %d", ErrInvalidQuery, op, start)
+			}
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q at position %d", ErrInvalidQuery, c, start)
+		}
+	}
+
+	return append(tokens, queryToken{kind: queryEOF, pos: len(q)}), nil
+}
+
+// queryCompareValue compares a field with a query value.
+// For the string operators ^=, $= and *=, the result is 0 if the field matches.
+// Returns false if the field and value cannot be compared.
+func queryCompareValue(field reflect.Value, value any, op string) (int, bool) {
+	if !field.IsValid() {
+		return 0, false
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if field.Kind() != reflect.Bool {
+			return 0, false
+		}
+		if field.Bool() == v {
+			return 0, true
+		}
+		return 1, op == "=" || op == "!="
+
+	case float64:
+		var n float64
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(field.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n = float64(field.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = field.Float()
+		default:
+			return 0, false
+		}
+		switch {
+		case n < v:
+			return -1, true
+		case n > v:
+			return 1, true
+		}
+		return 0, true
+
+	case string:
+		if field.Kind() != reflect.String {
+			return 0, false
+		}
+		s := field.String()
+		switch op {
+		case "^=":
+			return boolCompare(strings.HasPrefix(s, v)), true
+		case "$=":
+			return boolCompare(strings.HasSuffix(s, v)), true
+		case "*=":
+			return boolCompare(strings.Contains(s, v)), true
+		}
+		return strings.Compare(s, v), true
+	}
+
+	return 0, false
+}
+
+// queryField gets a field of a document by path.
+// Returns an invalid Value if the field does not exist.
+func queryField(v reflect.Value, path []string) reflect.Value {
+	for _, name := range path {
+		for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			return v
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := queryStructField(v.Type(), name)
+			if !ok {
+				return reflect.Value{}
+			}
+			fv, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				return reflect.Value{}
+			}
+			v = fv
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}
+		}
+	}
+
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	for v.IsValid() && v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() != reflect.Struct {
+		v = v.Elem()
+	}
+	return v
+}
+
+// queryStructField finds an exported struct field by its JSON name or, failing that, by its Go name ignoring case.
+func queryStructField(t reflect.Type, name string) (reflect.StructField, bool) {
+	var byName *reflect.StructField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag != "" && tag != "-" {
+			if tag == name {
+				return f, true
+			}
+		}
+		if byName == nil && strings.EqualFold(f.Name, name) {
+			byName = &f
+		}
+	}
+	if byName != nil {
+		return *byName, true
+	}
+	return reflect.StructField{}, false
+}
+
+func boolCompare(b bool) int {
+	if b {
+		return 0
+	}
+	return 1
+}
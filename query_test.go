@@ -0,0 +1,123 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	c := newStudentCollection(t)
+
+	queries := map[string][]string{
+		`age > 30`:                              {"annie", "ben"},
+		`age >= 21 AND age < 50`:                {"annie", "clive"},
+		`name ^= "A" OR name $= "e"`:            {"annie", "clive"},
+		`NOT (name *= "n" OR age == 21)`:        {},
+		`Name != "Ben" AND NOT age <= 21`:       {"annie"},
+		`@key = "ben"`:                          {"ben"},
+		`@key ^= "c" OR age = 32.0`:             {"annie", "clive"},
+		`name = null`:                           {},
+		`name != null AND (age < 0 OR age > 0)`: {"annie", "ben", "clive"},
+	}
+
+	for q, expected := range queries {
+		query, err := ParseQuery[*Student](q)
+		if err != nil {
+			t.Errorf("failed to parse query %s (%q)", q, err)
+			continue
+		}
+
+		iter := query.Apply(c.Iter()).SortKeys(func(a, b string) bool { return a < b })
+		if keys := iter.GetAllKeys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+			t.Errorf("incorrect students for query %s (expected %v, got %v)", q, expected, keys)
+		}
+		iter.Release()
+
+		iter = c.Iter().Filter(query.Filter())
+		if n := iter.Count(); n != len(expected) {
+			t.Errorf("incorrect count of students for filter %s (expected %d, got %d)", q, len(expected), n)
+		}
+		iter.Release()
+	}
+
+	invalid := []string{
+		``,
+		`age >`,
+		`age > 30 AND`,
+		`(age > 30`,
+		`score > 30`,
+		`age ~ 30`,
+		`age ^= 30`,
+		`name > null`,
+		`name = "Annie`,
+	}
+
+	for _, q := range invalid {
+		if _, err := ParseQuery[*Student](q); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery for query %s (got %q)", q, err)
+		}
+	}
+}
+
+func TestQueryUnicode(t *testing.T) {
+	type pupil struct {
+		Name string `json:"prénom"`
+	}
+
+	c := Memory[*pupil](nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	c.Put("zoe", &pupil{Name: "Zoë"})
+	c.Put("bob", &pupil{Name: "Bob"})
+
+	query, err := ParseQuery[*pupil](`prénom = "Zoë"`)
+	if err != nil {
+		t.Fatalf("failed to parse query with non-ASCII field (%q)", err)
+	}
+
+	iter := query.Apply(c.Iter())
+	defer iter.Release()
+
+	if keys := iter.GetAllKeys(); len(keys) != 1 || keys[0] != "zoe" {
+		t.Errorf("incorrect pupils (expected [zoe], got %v)", keys)
+	}
+
+	if _, err := ParseQuery[*pupil](`prénom = "Zoë" ™`); !errors.Is(err, ErrInvalidQuery) || !strings.Contains(err.Error(), `'™' at position 17`) {
+		t.Errorf("expected ErrInvalidQuery for unexpected character (got %q)", err)
+	}
+}
+
+func TestQueryLevelDB(t *testing.T) {
+	m := &countingMarshaler[*Student]{DocumentMarshaler: studentMarshaler}
+
+	path := ".leveldb/leveldb_query_test"
+	c := LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Destroy()
+
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	query, err := ParseQuery[*Student](`@key ^= "b" AND age > 30`)
+	if err != nil {
+		t.Fatalf("failed to parse query (%q)", err)
+	}
+
+	iter := query.Apply(c.Iter())
+	defer iter.Release()
+
+	if keys := iter.GetAllKeys(); len(keys) != 1 || keys[0] != "ben" {
+		t.Errorf("incorrect students (expected [ben], got %v)", keys)
+	}
+	if m.n != 1 {
+		t.Errorf("expected 1 student to be unmarshaled, got %d", m.n)
+	}
+}
@@ -0,0 +1,97 @@
+package ezdb
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// ScrubReport describes the outcome of scrubbing a collection.
+type ScrubReport struct {
+	Checked     int              // Number of documents checked.
+	Errors      []*DocumentError // Documents that could not be read, in key order.
+	Quarantined int              // Number of documents moved to quarantine.
+}
+
+// Scrub reads every document in the collection and reports those that cannot be unmarshaled, such as those failing a checksum.
+//
+// If quarantine is non-nil, the stored value of each bad document is put into it and the document is deleted from this collection, along with its entries in unique indexes.
+// A document that has been changed since it was read is not deleted.
+// Scrub continues past bad documents; an error is only returned if the collection itself cannot be read or written.
+func (c *LevelDBCollection[T]) Scrub(quarantine Collection[[]byte]) (*ScrubReport, error) {
+	r := &ScrubReport{Errors: []*DocumentError{}}
+	if c.db == nil {
+		return r, ErrClosed
+	}
+
+	iter := c.db.NewIterator(nil, c.optRead)
+	defer iter.Release()
+
+	for iter.Next() {
+		if !isDocumentKey(string(iter.Key())) {
+			continue
+		}
+
+		r.Checked++
+
+		err := c.m.Unmarshal(iter.Value(), c.m.Factory())
+		if err == nil {
+			continue
+		}
+
+		key := string(iter.Key())
+		r.Errors = append(r.Errors, &DocumentError{Key: key, Err: err})
+
+		if quarantine != nil {
+			if err := quarantine.Put(key, append([]byte{}, iter.Value()...)); err != nil {
+				return r, &DocumentError{Key: key, Err: err}
+			}
+			deleted, err := c.deleteUnreadable(iter.Key(), iter.Value())
+			if err != nil {
+				return r, &DocumentError{Key: key, Err: err}
+			}
+			if deleted {
+				r.Quarantined++
+			}
+		}
+	}
+
+	return r, iter.Error()
+}
+
+// deleteUnreadable deletes a document that cannot be unmarshaled, if its value is still src, along with its unique index entries.
+// The document's indexed values cannot be read, so its index entries are found by their owner instead.
+func (c *LevelDBCollection[T]) deleteUnreadable(key, src []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, err := c.db.Get(key, c.optRead)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(stored, src) {
+		return false, nil
+	}
+
+	batch := &leveldb.Batch{}
+	batch.Delete(key)
+
+	for _, u := range c.unique {
+		entries := c.db.NewIterator(util.BytesPrefix([]byte(uniqueIndexPrefix(u.name))), c.optRead)
+		for entries.Next() {
+			if bytes.Equal(entries.Value(), key) {
+				batch.Delete(append([]byte{}, entries.Key()...))
+			}
+		}
+		entries.Release()
+		if err := entries.Error(); err != nil {
+			return false, err
+		}
+	}
+
+	return true, c.db.Write(batch, c.optWrite)
+}
@@ -0,0 +1,115 @@
+package ezdb
+
+import "iter"
+
+// Result is a document or an error produced while iterating over a collection.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// collectionAll iterates over all documents in a collection.
+// Errors stop iteration but cannot be reported, as the iterator is not available to the caller. collectionResults reports them.
+func collectionAll[T any](c Collection[T]) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		for key, value := range c.Iter().All() {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+// collectionKeys iterates over all document keys in a collection.
+// Errors stop iteration but cannot be reported, as the iterator is not available to the caller. collectionResults reports them.
+func collectionKeys[T any](c Collection[T]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for key := range c.Iter().Keys() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// collectionResults iterates over all documents in a collection, including errors.
+func collectionResults[T any](c Collection[T]) iter.Seq2[string, Result[T]] {
+	return func(yield func(string, Result[T]) bool) {
+		i := c.Iter()
+		defer i.Release()
+
+		for ok := i.First(); ok; ok = i.Next() {
+			key, value, err := i.Get()
+			if !yield(key, Result[T]{Value: value, Err: err}) {
+				return
+			}
+		}
+
+		if err := i.Err(); err != nil {
+			var value T
+			yield("", Result[T]{Value: value, Err: err})
+		}
+	}
+}
+
+// collectionValues iterates over all document values in a collection.
+// Errors stop iteration but cannot be reported, as the iterator is not available to the caller. collectionResults reports them.
+func collectionValues[T any](c Collection[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for value := range c.Iter().Values() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// iterAll iterates over all documents in an iterator, then releases it.
+// Iteration stops at the first error, which is stored in err.
+func iterAll[T any](i Iterator[T], err *error) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		defer i.Release()
+		*err = nil
+
+		for ok := i.First(); ok; ok = i.Next() {
+			key, value, e := i.Get()
+			if e != nil {
+				*err = &DocumentError{Key: key, Err: e}
+				return
+			}
+			if !yield(key, value) {
+				return
+			}
+		}
+
+		*err = i.Err()
+	}
+}
+
+// iterKeys iterates over all document keys in an iterator, then releases it.
+func iterKeys[T any](i Iterator[T], err *error) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		defer i.Release()
+		*err = nil
+
+		for ok := i.First(); ok; ok = i.Next() {
+			if !yield(i.Key()) {
+				return
+			}
+		}
+
+		*err = i.Err()
+	}
+}
+
+// iterValues iterates over all document values in an iterator, then releases it.
+// Iteration stops at the first error, which is stored in err.
+func iterValues[T any](i Iterator[T], err *error) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range iterAll(i, err) {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package ezdb
+
+import "container/heap"
+
+// setCursor moves forward through an iterator used as input to a set operation.
+type setCursor[T any] struct {
+	i Iterator[T]
+
+	started bool
+	done    bool
+}
+
+func (c *setCursor[T]) next() (bool, error) {
+	if c.done {
+		return false, nil
+	}
+
+	var ok bool
+	if c.started {
+		ok = c.i.Next()
+	} else {
+		ok = c.i.First()
+		c.started = true
+	}
+
+	if !ok {
+		c.done = true
+		return false, c.i.Err()
+	}
+	return true, nil
+}
+
+// entry reads the current document as a stream entry.
+func (c *setCursor[T]) entry() streamEntry[T] {
+	key, value, err := c.i.Get()
+	return streamEntry[T]{key: key, value: streamValue(value, err)}
+}
+
+// mergeHeap merges the current documents of sorted iterators.
+type mergeHeap[T any] struct {
+	a []*mergeHead[T]
+	f SortFunc[T]
+}
+
+type mergeHead[T any] struct {
+	c     *setCursor[T]
+	n     int
+	key   string
+	value T
+	err   error
+}
+
+func (h *mergeHeap[T]) Len() int {
+	return len(h.a)
+}
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a := h.a[i]
+	b := h.a[j]
+
+	if h.f(a.value, b.value) {
+		return true
+	}
+	if h.f(b.value, a.value) {
+		return false
+	}
+	return a.n < b.n
+}
+
+func (h *mergeHeap[T]) Pop() any {
+	n := len(h.a) - 1
+	el := h.a[n]
+	h.a = h.a[:n]
+	return el
+}
+
+func (h *mergeHeap[T]) Push(el any) {
+	h.a = append(h.a, el.(*mergeHead[T]))
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) {
+	a := h.a[i]
+	b := h.a[j]
+	h.a[i] = b
+	h.a[j] = a
+}
+
+// Difference creates an iterator with the documents of the first iterator whose keys are not in any other iterator.
+// Documents are read lazily from the first iterator, in its order, and only the keys of other iterators are read.
+// However, the keys of every other iterator are held in memory, as are documents already read so that the new iterator can move backward.
+//
+// Releasing the new iterator releases all of the given iterators.
+func Difference[T any](first Iterator[T], others ...Iterator[T]) Iterator[T] {
+	var exclude map[string]bool
+	c := &setCursor[T]{i: first}
+
+	return newStreamIterator(func() (streamEntry[T], bool, error) {
+		if exclude == nil {
+			exclude = keySet(others)
+		}
+
+		for {
+			ok, err := c.next()
+			if !ok {
+				return streamEntry[T]{}, false, err
+			}
+			if !exclude[c.i.Key()] {
+				return c.entry(), true, nil
+			}
+		}
+	}, releaseAll(append([]Iterator[T]{first}, others...)))
+}
+
+// Intersect creates an iterator with the documents of the first iterator whose keys are in every other iterator.
+// Documents are read lazily from the first iterator, in its order, and only the keys of other iterators are read.
+// However, the keys of every other iterator are held in memory, as are documents already read so that the new iterator can move backward.
+//
+// Releasing the new iterator releases all of the given iterators.
+func Intersect[T any](iters ...Iterator[T]) Iterator[T] {
+	if len(iters) == 0 {
+		return newStreamIterator(func() (streamEntry[T], bool, error) {
+			return streamEntry[T]{}, false, nil
+		}, nil)
+	}
+
+	var include []map[string]bool
+	c := &setCursor[T]{i: iters[0]}
+
+	return newStreamIterator(func() (streamEntry[T], bool, error) {
+		if include == nil {
+			include = []map[string]bool{}
+			for _, i := range iters[1:] {
+				include = append(include, keySet([]Iterator[T]{i}))
+			}
+		}
+
+	next:
+		for {
+			ok, err := c.next()
+			if !ok {
+				return streamEntry[T]{}, false, err
+			}
+			for _, keys := range include {
+				if !keys[c.i.Key()] {
+					continue next
+				}
+			}
+			return c.entry(), true, nil
+		}
+	}, releaseAll(iters))
+}
+
+// MergeSorted creates an iterator that merges documents from iterators that are each already sorted by f.
+// Documents that are equal are taken from iterators in the order given, so the merge is stable.
+// Documents with the same key in more than one iterator are all included.
+// Documents are read lazily, but those already read are held in memory so that the new iterator can move backward.
+//
+// Releasing the new iterator releases all of the given iterators.
+func MergeSorted[T any](f SortFunc[T], iters ...Iterator[T]) Iterator[T] {
+	var h *mergeHeap[T]
+
+	advance := func(head *mergeHead[T]) (bool, error) {
+		ok, err := head.c.next()
+		if ok {
+			head.key, head.value, head.err = head.c.i.Get()
+		}
+		return ok, err
+	}
+
+	return newStreamIterator(func() (streamEntry[T], bool, error) {
+		if h == nil {
+			h = &mergeHeap[T]{a: []*mergeHead[T]{}, f: f}
+			for n, i := range iters {
+				head := &mergeHead[T]{c: &setCursor[T]{i: i}, n: n}
+				ok, err := advance(head)
+				if err != nil {
+					return streamEntry[T]{}, false, err
+				}
+				if ok {
+					h.a = append(h.a, head)
+				}
+			}
+			heap.Init(h)
+		}
+
+		if h.Len() == 0 {
+			return streamEntry[T]{}, false, nil
+		}
+
+		head := h.a[0]
+		e := streamEntry[T]{key: head.key, value: streamValue(head.value, head.err)}
+
+		ok, err := advance(head)
+		if err != nil {
+			return streamEntry[T]{}, false, err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+
+		return e, true, nil
+	}, releaseAll(iters))
+}
+
+// Union creates an iterator with the documents of every iterator, in the order given.
+// If a key is in more than one iterator, the document from the first of them is used.
+// Documents are read lazily, but those already read are held in memory so that the new iterator can move backward, as are the keys of documents already seen.
+//
+// Releasing the new iterator releases all of the given iterators.
+func Union[T any](iters ...Iterator[T]) Iterator[T] {
+	seen := map[string]bool{}
+	n := 0
+	var c *setCursor[T]
+
+	return newStreamIterator(func() (streamEntry[T], bool, error) {
+		for n < len(iters) {
+			if c == nil {
+				c = &setCursor[T]{i: iters[n]}
+			}
+
+			ok, err := c.next()
+			if err != nil {
+				return streamEntry[T]{}, false, err
+			}
+			if !ok {
+				c = nil
+				n++
+				continue
+			}
+
+			if key := c.i.Key(); !seen[key] {
+				seen[key] = true
+				return c.entry(), true, nil
+			}
+		}
+		return streamEntry[T]{}, false, nil
+	}, releaseAll(iters))
+}
+
+// keySet gets the keys of all documents in some iterators.
+func keySet[T any](iters []Iterator[T]) map[string]bool {
+	keys := map[string]bool{}
+	for _, i := range iters {
+		for _, key := range i.GetAllKeys() {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// releaseAll creates a function that releases some iterators.
+func releaseAll[T any](iters []Iterator[T]) func() {
+	return func() {
+		for _, i := range iters {
+			i.Release()
+		}
+	}
+}
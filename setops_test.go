@@ -0,0 +1,75 @@
+package ezdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func newSetOpsCollection(t *testing.T, keys ...string) *MemoryCollection[*Student] {
+	c := Memory[*Student](nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	for _, key := range keys {
+		if err := c.Put(key, students[key]); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+	return c
+}
+
+func TestSetOps(t *testing.T) {
+	a := newSetOpsCollection(t, "annie", "ben")
+	b := newSetOpsCollection(t, "ben", "clive")
+
+	byAge := By(func(s *Student) int { return s.Age }).SortFunc()
+	byKey := func(a, b string) bool { return a < b }
+
+	tests := map[string]struct {
+		Iter     Iterator[*Student]
+		Expected string
+	}{
+		"union":        {Union(a.Iter().SortKeys(byKey), b.Iter().SortKeys(byKey)), "annie,ben,clive"},
+		"intersect":    {Intersect(a.Iter(), b.Iter()), "ben"},
+		"difference":   {Difference(a.Iter(), b.Iter()), "annie"},
+		"merge sorted": {MergeSorted(byAge, a.Iter().Sort(byAge), b.Iter().Sort(byAge)), "clive,annie,ben,ben"},
+		"empty":        {Intersect[*Student](), ""},
+	}
+
+	for name, test := range tests {
+		actual := strings.Join(collectKeys(test.Iter), ",")
+		if actual != test.Expected {
+			t.Errorf("%s: incorrect students (expected '%s', got '%s')", name, test.Expected, actual)
+		}
+
+		// Moving backward should revisit the same documents
+		if test.Expected != "" {
+			keys := strings.Split(test.Expected, ",")
+			if !test.Iter.Last() || test.Iter.Key() != keys[len(keys)-1] {
+				t.Errorf("%s: incorrect last student (expected '%s', got '%s')", name, keys[len(keys)-1], test.Iter.Key())
+			}
+			if value, err := test.Iter.Value(); err != nil {
+				t.Errorf("%s: failed to get last student (%q)", name, err)
+			} else if err := compareStudent(keys[len(keys)-1], students[keys[len(keys)-1]], value); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+
+		test.Iter.Release()
+	}
+}
+
+func TestSetOpsRelease(t *testing.T) {
+	a := newSetOpsCollection(t, "annie", "ben").Iter()
+	b := newSetOpsCollection(t, "ben", "clive").Iter()
+
+	u := Union(a, b).Limit(1)
+	u.Release()
+
+	if _, err := a.GetAll(); err != ErrReleased {
+		t.Errorf("expected first iterator to be released, got %v", err)
+	}
+	if _, err := b.GetAll(); err != ErrReleased {
+		t.Errorf("expected second iterator to be released, got %v", err)
+	}
+}
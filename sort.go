@@ -1,5 +1,7 @@
 package ezdb
 
+import "container/heap"
+
 type sortable[T any] struct {
 	Key   string
 	Value T
@@ -60,10 +62,98 @@ func (s *valueSort[T]) Swap(i, j int) {
 	s.a[j] = a
 }
 
-func makeSortable[T any](m map[string]T) []*sortable[T] {
+func makeSortable[T any](k []string, m map[string]T) []*sortable[T] {
 	a := []*sortable[T]{}
-	for key, value := range m {
-		a = append(a, &sortable[T]{Key: key, Value: value})
+	for _, key := range k {
+		a = append(a, &sortable[T]{Key: key, Value: m[key]})
 	}
 	return a
 }
+
+// topHeap keeps the first documents in sort order.
+// The root is the document that sorts last, so it can be replaced by any document that sorts before it.
+type topHeap[T any] struct {
+	a []*topEntry[T]
+	f SortFunc[T]
+}
+
+type topEntry[T any] struct {
+	Key   string
+	Value T
+	seq   int
+}
+
+func (h *topHeap[T]) Len() int {
+	return len(h.a)
+}
+
+func (h *topHeap[T]) Less(i, j int) bool {
+	return h.after(h.a[i], h.a[j])
+}
+
+func (h *topHeap[T]) Pop() any {
+	n := len(h.a) - 1
+	el := h.a[n]
+	h.a = h.a[:n]
+	return el
+}
+
+func (h *topHeap[T]) Push(el any) {
+	h.a = append(h.a, el.(*topEntry[T]))
+}
+
+func (h *topHeap[T]) Swap(i, j int) {
+	a := h.a[i]
+	b := h.a[j]
+	h.a[i] = b
+	h.a[j] = a
+}
+
+// after returns true if a sorts after b.
+// Documents that are equal sort in the order they were seen, as with a stable sort.
+func (h *topHeap[T]) after(a, b *topEntry[T]) bool {
+	if h.f(b.Value, a.Value) {
+		return true
+	}
+	if h.f(a.Value, b.Value) {
+		return false
+	}
+	return a.seq > b.seq
+}
+
+// topK creates a MemoryIterator with the first n documents of i in sort order.
+// Only n documents are held at a time.
+// If a document cannot be read, topK stops there and returns a DocumentError with the documents read so far.
+func topK[T any](i Iterator[T], n int, f SortFunc[T]) (*MemoryIterator[T], error) {
+	h := &topHeap[T]{a: []*topEntry[T]{}, f: f}
+
+	var failed error
+	seq := 0
+	for ok := i.First(); ok && n > 0; ok = i.Next() {
+		key, value, err := i.Get()
+		if err != nil {
+			failed = &DocumentError{Key: key, Err: err}
+			break
+		}
+
+		el := &topEntry[T]{Key: key, Value: value, seq: seq}
+		seq++
+
+		if h.Len() < n {
+			heap.Push(h, el)
+		} else if h.after(h.a[0], el) {
+			h.a[0] = el
+			heap.Fix(h, 0)
+		}
+	}
+
+	k := make([]string, h.Len())
+	m := make(map[string]T, h.Len())
+	for n := len(k) - 1; n >= 0; n-- {
+		el := heap.Pop(h).(*topEntry[T])
+		k[n] = el.Key
+		m[el.Key] = el.Value
+	}
+
+	return newMemoryIterator(m, k, i), failed
+}
@@ -0,0 +1,405 @@
+package ezdb
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Maximum number of runs merged at once, which limits the number of files open while sorting.
+// If there are more runs, they are merged in several passes.
+var sortMergeFanIn = 64
+
+// sortRun is a file of documents sorted by a SortFunc.
+// Each record is a uvarint-prefixed key followed by a uvarint-prefixed value, in marshaled form.
+// The file is only open while the run is being written or merged.
+type sortRun struct {
+	path string
+	f    *os.File
+	r    *bufio.Reader
+	off  int64
+}
+
+// sortRecord is a document held in memory while sorting.
+type sortRecord[T any] struct {
+	key   string
+	raw   []byte
+	value T
+
+	run int
+	off int64 // Offset of the value in the run
+}
+
+// sortHeap merges the heads of sorted runs.
+type sortHeap[T any] struct {
+	a []*sortRecord[T]
+	f SortFunc[T]
+}
+
+func (h *sortHeap[T]) Len() int {
+	return len(h.a)
+}
+
+func (h *sortHeap[T]) Less(i, j int) bool {
+	a := h.a[i]
+	b := h.a[j]
+
+	if h.f(a.value, b.value) {
+		return true
+	}
+	if h.f(b.value, a.value) {
+		return false
+	}
+	// Runs are created in iteration order, so preferring the earlier run keeps the sort stable
+	return a.run < b.run
+}
+
+func (h *sortHeap[T]) Pop() any {
+	n := len(h.a) - 1
+	el := h.a[n]
+	h.a = h.a[:n]
+	return el
+}
+
+func (h *sortHeap[T]) Push(el any) {
+	h.a = append(h.a, el.(*sortRecord[T]))
+}
+
+func (h *sortHeap[T]) Swap(i, j int) {
+	a := h.a[i]
+	b := h.a[j]
+	h.a[i] = b
+	h.a[j] = a
+}
+
+func (r *sortRun) close() error {
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	r.r = nil
+	return err
+}
+
+func (r *sortRun) open() error {
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	r.f = f
+	r.r = bufio.NewReader(f)
+	r.off = 0
+	return nil
+}
+
+func (r *sortRun) remove() error {
+	if err := r.close(); err != nil {
+		return err
+	}
+	return os.Remove(r.path)
+}
+
+// externalSort sorts the documents in a LevelDB iterator, spilling sorted runs to disk whenever the documents held in memory exceed budget bytes.
+// Runs are merged lazily as the returned iterator moves forward.
+//
+// The budget is measured against the marshaled size of keys and values.
+// Documents are also held in memory in unmarshaled form until they are written to a run, and this is not counted, so actual memory use may be several times the budget.
+// If all documents fit in the budget, they are sorted in memory.
+func externalSort[T any](i *LevelDBIterator[T], f SortFunc[T], budget int, dir string) Iterator[T] {
+	tmp := ""
+	created := []*sortRun{}
+	runs := []*sortRun{}
+
+	cleanup := func() {
+		for _, run := range created {
+			run.close()
+		}
+		if tmp != "" {
+			os.RemoveAll(tmp)
+		}
+	}
+	fail := func(err error) Iterator[T] {
+		cleanup()
+		return newStreamIterator(func() (streamEntry[T], bool, error) {
+			return streamEntry[T]{}, false, err
+		}, i.Release)
+	}
+
+	create := func() (*sortRun, error) {
+		if tmp == "" {
+			var err error
+			if tmp, err = os.MkdirTemp(dir, "ezdb-sort-"); err != nil {
+				return nil, err
+			}
+		}
+
+		run := &sortRun{path: filepath.Join(tmp, fmt.Sprintf("run-%d", len(created)))}
+		file, err := os.Create(run.path)
+		if err != nil {
+			return nil, err
+		}
+		run.f = file
+		created = append(created, run)
+		return run, nil
+	}
+
+	chunk := []*sortRecord[T]{}
+	size := 0
+
+	flush := func() error {
+		sort.SliceStable(chunk, func(a, b int) bool {
+			return f(chunk[a].value, chunk[b].value)
+		})
+
+		run, err := create()
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+
+		w := bufio.NewWriter(run.f)
+		for _, rec := range chunk {
+			if err := writeSortRecord(w, rec.key, rec.raw); err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := run.close(); err != nil {
+			return err
+		}
+
+		chunk = chunk[:0]
+		size = 0
+		return nil
+	}
+
+	for ok := i.First(); ok; ok = i.Next() {
+		key, value, err := i.Get()
+		if err != nil {
+			return fail(&DocumentError{Key: key, Err: err})
+		}
+
+		raw := append([]byte{}, i.i.Value()...)
+		chunk = append(chunk, &sortRecord[T]{key: key, raw: raw, value: value})
+		size += len(key) + len(raw)
+
+		if size >= budget {
+			if err := flush(); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if err := i.Err(); err != nil {
+		return fail(err)
+	}
+
+	// Everything fit in memory, so there is no need to merge
+	if len(runs) == 0 {
+		sort.SliceStable(chunk, func(a, b int) bool {
+			return f(chunk[a].value, chunk[b].value)
+		})
+
+		k := make([]string, 0, len(chunk))
+		m := make(map[string]T, len(chunk))
+		for _, rec := range chunk {
+			k = append(k, rec.key)
+			m[rec.key] = rec.value
+		}
+		return newMemoryIterator(m, k, i)
+	}
+
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Merge consecutive groups of runs until few enough remain to be opened at once.
+	// Each group is merged into a single run in order, so the sort remains stable
+	for len(runs) > sortMergeFanIn {
+		merged := []*sortRun{}
+		for start := 0; start < len(runs); start += sortMergeFanIn {
+			run, err := create()
+			if err != nil {
+				return fail(err)
+			}
+			if err := mergeSortRuns(runs[start:min(start+sortMergeFanIn, len(runs))], run, f, i.m); err != nil {
+				return fail(err)
+			}
+			merged = append(merged, run)
+		}
+		runs = merged
+	}
+
+	// Start merging with the head of each run
+	h, err := openSortHeap(runs, f, i.m)
+	if err != nil {
+		return fail(err)
+	}
+
+	// The most recently merged document, which has already been unmarshaled
+	var current *sortRecord[T]
+	popped := 0
+
+	next := func() (streamEntry[T], bool, error) {
+		if h.Len() == 0 {
+			return streamEntry[T]{}, false, nil
+		}
+
+		rec := heap.Pop(h).(*sortRecord[T])
+		next, err := readSortRecord(runs[rec.run], rec.run, i.m)
+		if err != nil {
+			return streamEntry[T]{}, false, err
+		}
+		if next != nil {
+			heap.Push(h, next)
+		}
+
+		current = rec
+		popped++
+		n := popped
+
+		// Only the most recently merged value is held in memory.
+		// Earlier values are read back from the run when needed
+		file := runs[rec.run].f
+		off := rec.off
+		size := len(rec.raw)
+		return streamEntry[T]{
+			key: rec.key,
+			value: func() (T, error) {
+				if n == popped {
+					return current.value, nil
+				}
+
+				value := i.m.Factory()
+				raw := make([]byte, size)
+				if _, err := file.ReadAt(raw, off); err != nil {
+					return value, err
+				}
+				err := i.m.Unmarshal(raw, value)
+				return value, err
+			},
+		}, true, nil
+	}
+
+	return newStreamIterator(next, func() {
+		cleanup()
+		i.Release()
+	})
+}
+
+// mergeSortRuns merges sorted runs into a single run, then removes them.
+func mergeSortRuns[T any](runs []*sortRun, dest *sortRun, f SortFunc[T], m DocumentMarshaler[T, []byte]) error {
+	h, err := openSortHeap(runs, f, m)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(dest.f)
+	for h.Len() > 0 {
+		rec := heap.Pop(h).(*sortRecord[T])
+		if err := writeSortRecord(w, rec.key, rec.raw); err != nil {
+			return err
+		}
+
+		next, err := readSortRecord(runs[rec.run], rec.run, m)
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			heap.Push(h, next)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, run := range runs {
+		if err := run.remove(); err != nil {
+			return err
+		}
+	}
+	return dest.close()
+}
+
+// openSortHeap opens sorted runs and reads the first record from each.
+func openSortHeap[T any](runs []*sortRun, f SortFunc[T], m DocumentMarshaler[T, []byte]) (*sortHeap[T], error) {
+	h := &sortHeap[T]{a: []*sortRecord[T]{}, f: f}
+	for n, run := range runs {
+		if err := run.open(); err != nil {
+			return nil, err
+		}
+		rec, err := readSortRecord(run, n, m)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil {
+			h.a = append(h.a, rec)
+		}
+	}
+	heap.Init(h)
+	return h, nil
+}
+
+// readSortRecord reads the next record from a sorted run.
+// Returns nil if the run has ended.
+func readSortRecord[T any](run *sortRun, n int, m DocumentMarshaler[T, []byte]) (*sortRecord[T], error) {
+	key, _, err := readSortBytes(run)
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	raw, off, err := readSortBytes(run)
+	if err != nil {
+		return nil, err
+	}
+
+	value := m.Factory()
+	if err := m.Unmarshal(raw, value); err != nil {
+		return nil, &DocumentError{Key: string(key), Err: err}
+	}
+
+	return &sortRecord[T]{key: string(key), raw: raw, value: value, run: n, off: off}, nil
+}
+
+// writeSortRecord writes a record to a sorted run.
+func writeSortRecord(w *bufio.Writer, key string, raw []byte) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	for _, b := range [][]byte{[]byte(key), raw} {
+		n := binary.PutUvarint(buf, uint64(len(b)))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSortBytes reads uvarint-prefixed bytes from a sorted run, returning the bytes and their offset in the run.
+func readSortBytes(run *sortRun) ([]byte, int64, error) {
+	size, err := binary.ReadUvarint(run.r)
+	if err != nil {
+		return nil, 0, err
+	}
+	run.off += int64(len(binary.AppendUvarint(nil, size)))
+	off := run.off
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(run.r, b); err != nil {
+		return nil, 0, err
+	}
+	run.off += int64(size)
+	return b, off, nil
+}
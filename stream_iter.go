@@ -0,0 +1,336 @@
+package ezdb
+
+import (
+	"iter"
+	"strings"
+)
+
+// streamEntry is a document produced by a stream.
+// Values are loaded on demand.
+type streamEntry[T any] struct {
+	key   string
+	value func() (T, error)
+}
+
+// streamIterator is an Iterator over documents produced lazily by a function.
+//
+// Documents are pulled from the stream as the iterator moves forward and retained so that it can move backward again.
+// Keys and the means to load values are retained, so memory use grows with the number of documents read.
+// If the stream loads values on demand, such as from a sorted run, only keys are held; otherwise, every value read is held too.
+type streamIterator[T any] struct {
+	next    func() (streamEntry[T], bool, error)
+	release func()
+
+	entries []streamEntry[T]
+	done    bool
+	failed  error
+
+	// Whether keys are in descending order, which changes the direction of Seek.
+	desc bool
+
+	err      error
+	pos      int
+	released bool
+}
+
+func (i *streamIterator[T]) All() iter.Seq2[string, T] {
+	return iterAll[T](i, &i.err)
+}
+
+func (i *streamIterator[T]) Count() int {
+	i.drain()
+	return len(i.entries)
+}
+
+func (i *streamIterator[T]) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.failed
+}
+
+// Filter creates a new iterator with a subset of documents.
+// If a document cannot be read, the new iterator ends there and its Err reports the error.
+func (i *streamIterator[T]) Filter(f FilterFunc[T]) Iterator[T] {
+	n := 0
+	return i.derive(func() (streamEntry[T], bool, error) {
+		for {
+			e, ok, err := i.at(n)
+			n++
+			if !ok {
+				return e, false, err
+			}
+
+			value, err := e.value()
+			if err != nil {
+				return e, false, &DocumentError{Key: e.key, Err: err}
+			}
+			if f(e.key, value) {
+				return e, true, nil
+			}
+		}
+	})
+}
+
+func (i *streamIterator[T]) First() bool {
+	i.pos = 0
+	return i.fill(0)
+}
+
+func (i *streamIterator[T]) Get() (string, T, error) {
+	value, err := i.Value()
+	return i.Key(), value, err
+}
+
+func (i *streamIterator[T]) GetAll() (map[string]T, error) {
+	m := map[string]T{}
+	if i.released {
+		return m, ErrReleased
+	}
+
+	for ok := i.First(); ok; ok = i.Next() {
+		key, value, err := i.Get()
+		if err != nil {
+			return m, &DocumentError{Key: key, Err: err}
+		}
+		m[key] = value
+	}
+	return m, i.failed
+}
+
+func (i *streamIterator[T]) GetAllKeys() []string {
+	keys := []string{}
+	for ok := i.First(); ok; ok = i.Next() {
+		keys = append(keys, i.Key())
+	}
+	return keys
+}
+
+func (i *streamIterator[T]) Key() string {
+	if i.pos < 0 || i.pos >= len(i.entries) {
+		return ""
+	}
+	return i.entries[i.pos].key
+}
+
+func (i *streamIterator[T]) KeyFilter(f KeyFilterFunc) Iterator[T] {
+	n := 0
+	return i.derive(func() (streamEntry[T], bool, error) {
+		for {
+			e, ok, err := i.at(n)
+			n++
+			if !ok || f(e.key) {
+				return e, ok, err
+			}
+		}
+	})
+}
+
+func (i *streamIterator[T]) Keys() iter.Seq[string] {
+	return iterKeys[T](i, &i.err)
+}
+
+func (i *streamIterator[T]) Last() bool {
+	i.drain()
+	i.pos = len(i.entries) - 1
+	return i.pos >= 0
+}
+
+func (i *streamIterator[T]) Limit(n int) Iterator[T] {
+	j := 0
+	return i.derive(func() (streamEntry[T], bool, error) {
+		if n >= 0 && j >= n {
+			return streamEntry[T]{}, false, nil
+		}
+		e, ok, err := i.at(j)
+		j++
+		return e, ok, err
+	})
+}
+
+func (i *streamIterator[T]) Next() bool {
+	if i.pos >= len(i.entries) {
+		return false
+	}
+	i.pos++
+	return i.fill(i.pos)
+}
+
+func (i *streamIterator[T]) Prefix(prefix string) Iterator[T] {
+	return i.KeyFilter(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+func (i *streamIterator[T]) Prev() bool {
+	if i.pos < 0 {
+		return false
+	}
+	i.pos--
+	return i.pos >= 0
+}
+
+func (i *streamIterator[T]) Release() {
+	if i.released {
+		return
+	}
+
+	i.entries = nil
+	i.done = true
+	i.released = true
+
+	if i.release != nil {
+		i.release()
+	}
+}
+
+func (i *streamIterator[T]) Reverse() Iterator[T] {
+	i.drain()
+
+	entries := make([]streamEntry[T], len(i.entries))
+	for n, e := range i.entries {
+		entries[len(entries)-1-n] = e
+	}
+
+	r := newStreamIterator[T](nil, i.Release)
+	r.entries = entries
+	r.done = true
+	r.failed = i.failed
+	r.desc = !i.desc
+	return r
+}
+
+func (i *streamIterator[T]) Seek(key string) bool {
+	for n := 0; i.fill(n); n++ {
+		if k := i.entries[n].key; (!i.desc && k >= key) || (i.desc && k <= key) {
+			i.pos = n
+			return true
+		}
+	}
+	i.pos = len(i.entries)
+	return false
+}
+
+func (i *streamIterator[T]) Skip(n int) Iterator[T] {
+	j := max(n, 0)
+	return i.derive(func() (streamEntry[T], bool, error) {
+		e, ok, err := i.at(j)
+		j++
+		return e, ok, err
+	})
+}
+
+// Sort creates a new iterator with documents sorted by f.
+// If a document cannot be read, sorting stops there and Err on this iterator reports the error.
+func (i *streamIterator[T]) Sort(f SortFunc[T]) Iterator[T] {
+	return i.materialize().Sort(f)
+}
+
+// SortKeys creates a new iterator with documents sorted by key using f.
+// If a document cannot be read, sorting stops there and Err on this iterator reports the error.
+func (i *streamIterator[T]) SortKeys(f SortFunc[string]) Iterator[T] {
+	return i.materialize().SortKeys(f)
+}
+
+// TopK creates a new iterator with the first n sorted documents, without sorting every document.
+// If a document cannot be read, TopK stops there and Err on this iterator reports the error.
+func (i *streamIterator[T]) TopK(n int, f SortFunc[T]) Iterator[T] {
+	top, err := topK[T](i, n, f)
+	i.err = err
+	return top
+}
+
+func (i *streamIterator[T]) Value() (T, error) {
+	if i.released {
+		var value T
+		return value, ErrReleased
+	}
+	if i.pos < 0 || i.pos >= len(i.entries) {
+		var value T
+		return value, ErrNotFound
+	}
+	return i.entries[i.pos].value()
+}
+
+func (i *streamIterator[T]) Values() iter.Seq[T] {
+	return iterValues[T](i, &i.err)
+}
+
+// at gets the document at position n, pulling documents from the stream if necessary.
+func (i *streamIterator[T]) at(n int) (streamEntry[T], bool, error) {
+	if !i.fill(n) {
+		return streamEntry[T]{}, false, i.failed
+	}
+	return i.entries[n], true, nil
+}
+
+// derive creates a new stream iterator that pulls documents from this one.
+// Releasing the new iterator also releases this one.
+func (i *streamIterator[T]) derive(next func() (streamEntry[T], bool, error)) *streamIterator[T] {
+	d := newStreamIterator(next, i.Release)
+	d.desc = i.desc
+	return d
+}
+
+// drain pulls all remaining documents from the stream.
+func (i *streamIterator[T]) drain() {
+	for i.fill(len(i.entries)) {
+	}
+}
+
+// fill pulls documents from the stream until there is a document at position n.
+// Returns false if the stream ends first.
+func (i *streamIterator[T]) fill(n int) bool {
+	for len(i.entries) <= n && !i.done {
+		e, ok, err := i.next()
+		if err != nil {
+			i.failed = err
+			i.done = true
+		} else if !ok {
+			i.done = true
+		} else {
+			i.entries = append(i.entries, e)
+		}
+	}
+	return n >= 0 && n < len(i.entries)
+}
+
+// materialize reads all documents into a MemoryIterator, preserving order.
+// If a document cannot be read, reading stops there and Err on this iterator reports the error.
+// Releasing the new iterator also releases this one.
+func (i *streamIterator[T]) materialize() *MemoryIterator[T] {
+	k := []string{}
+	m := map[string]T{}
+	i.err = nil
+	for ok := i.First(); ok; ok = i.Next() {
+		key, value, err := i.Get()
+		if err != nil {
+			i.err = &DocumentError{Key: key, Err: err}
+			break
+		}
+		if _, ok := m[key]; !ok {
+			k = append(k, key)
+		}
+		m[key] = value
+	}
+	r := newMemoryIterator(m, k, i)
+	r.desc = i.desc
+	return r
+}
+
+func newStreamIterator[T any](next func() (streamEntry[T], bool, error), release func()) *streamIterator[T] {
+	return &streamIterator[T]{
+		next:    next,
+		release: release,
+
+		entries: []streamEntry[T]{},
+		pos:     -1,
+	}
+}
+
+// streamValue creates a function that loads a value already held in memory.
+func streamValue[T any](value T, err error) func() (T, error) {
+	return func() (T, error) {
+		return value, err
+	}
+}
@@ -0,0 +1,280 @@
+package ezdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Tuple is a composite key, such as ("tenant", 42, "order", ts).
+//
+// Tuples are encoded by TupleKeyCodec using the FoundationDB tuple layer encoding, which preserves the order of elements.
+// The encoding of a tuple is a prefix of the encoding of any longer tuple with the same leading elements, so a collection can be prefix-scanned by any of them.
+//
+// Elements may be nil, []byte, string, bool, any integer or float type, time.Time or a nested Tuple.
+// When decoded, integers become int64 (or uint64 if too large for int64), float32 becomes float64, and time.Time becomes int64 Unix nanoseconds.
+type Tuple []any
+
+// TupleKeyCodec encodes tuple keys.
+type TupleKeyCodec struct{}
+
+// Tuple element type codes.
+const (
+	tupleNil    byte = 0x00
+	tupleBytes  byte = 0x01
+	tupleString byte = 0x02
+	tupleNested byte = 0x05
+	tupleInt    byte = 0x14 // Zero. Other integers are offset by their length in bytes.
+	tupleFloat  byte = 0x21
+	tupleFalse  byte = 0x26
+	tupleTrue   byte = 0x27
+)
+
+func (c *TupleKeyCodec) Decode(key string) (Tuple, error) {
+	t, rest, err := decodeTuple([]byte(key), false)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: unexpected data after tuple", ErrInvalidKey)
+	}
+	return t, nil
+}
+
+func (c *TupleKeyCodec) Encode(key Tuple) (string, error) {
+	b, err := appendTuple(nil, key, false)
+	return string(b), err
+}
+
+// appendTuple appends the encoding of a tuple to b.
+// Nested tuples encode nil differently so that their end can be found.
+func appendTuple(b []byte, t Tuple, nested bool) ([]byte, error) {
+	for _, v := range t {
+		switch v := v.(type) {
+		case nil:
+			b = append(b, tupleNil)
+			if nested {
+				b = append(b, 0xff)
+			}
+		case []byte:
+			b = appendTupleBytes(append(b, tupleBytes), v)
+		case string:
+			b = appendTupleBytes(append(b, tupleString), []byte(v))
+		case bool:
+			if v {
+				b = append(b, tupleTrue)
+			} else {
+				b = append(b, tupleFalse)
+			}
+		case int:
+			b = appendTupleInt(b, int64(v))
+		case int8:
+			b = appendTupleInt(b, int64(v))
+		case int16:
+			b = appendTupleInt(b, int64(v))
+		case int32:
+			b = appendTupleInt(b, int64(v))
+		case int64:
+			b = appendTupleInt(b, v)
+		case uint:
+			b = appendTupleUint(b, uint64(v))
+		case uint8:
+			b = appendTupleUint(b, uint64(v))
+		case uint16:
+			b = appendTupleUint(b, uint64(v))
+		case uint32:
+			b = appendTupleUint(b, uint64(v))
+		case uint64:
+			b = appendTupleUint(b, v)
+		case float32:
+			b = appendTupleFloat(b, float64(v))
+		case float64:
+			b = appendTupleFloat(b, v)
+		case time.Time:
+			b = appendTupleInt(b, v.UnixNano())
+		case Tuple:
+			var err error
+			if b, err = appendTuple(append(b, tupleNested), v, true); err != nil {
+				return nil, err
+			}
+			b = append(b, 0x00)
+		default:
+			return nil, fmt.Errorf("%w: unsupported tuple element type %T", ErrInvalidKey, v)
+		}
+	}
+	return b, nil
+}
+
+// appendTupleBytes appends a byte string, escaping null bytes and terminating it with a null byte.
+func appendTupleBytes(b, v []byte) []byte {
+	for _, c := range v {
+		b = append(b, c)
+		if c == 0x00 {
+			b = append(b, 0xff)
+		}
+	}
+	return append(b, 0x00)
+}
+
+func appendTupleFloat(b []byte, v float64) []byte {
+	bits := math.Float64bits(v)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	return binary.BigEndian.AppendUint64(append(b, tupleFloat), bits)
+}
+
+func appendTupleInt(b []byte, v int64) []byte {
+	if v >= 0 {
+		return appendTupleUint(b, uint64(v))
+	}
+
+	abs := uint64(-v)
+	n := tupleIntLength(abs)
+	mask := uint64(math.MaxUint64) >> (64 - 8*n)
+	return appendTupleIntBytes(append(b, tupleInt-byte(n)), ^abs&mask, n)
+}
+
+func appendTupleIntBytes(b []byte, v uint64, n int) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[8-n:]...)
+}
+
+func appendTupleUint(b []byte, v uint64) []byte {
+	n := tupleIntLength(v)
+	return appendTupleIntBytes(append(b, tupleInt+byte(n)), v, n)
+}
+
+// decodeTuple decodes a tuple, returning any remaining data.
+// A nested tuple ends at an unescaped null byte, which is consumed.
+func decodeTuple(b []byte, nested bool) (Tuple, []byte, error) {
+	t := Tuple{}
+
+	for len(b) > 0 {
+		code := b[0]
+		b = b[1:]
+
+		switch {
+		case code == tupleNil:
+			if !nested {
+				t = append(t, nil)
+			} else if len(b) > 0 && b[0] == 0xff {
+				t = append(t, nil)
+				b = b[1:]
+			} else {
+				return t, b, nil
+			}
+
+		case code == tupleBytes || code == tupleString:
+			v, rest, err := decodeTupleBytes(b)
+			if err != nil {
+				return nil, nil, err
+			}
+			if code == tupleBytes {
+				t = append(t, v)
+			} else {
+				t = append(t, string(v))
+			}
+			b = rest
+
+		case code == tupleNested:
+			v, rest, err := decodeTuple(b, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			t = append(t, v)
+			b = rest
+
+		case code >= tupleInt-8 && code <= tupleInt+8:
+			n := int(code) - int(tupleInt)
+			neg := n < 0
+			if neg {
+				n = -n
+			}
+			if len(b) < n {
+				return nil, nil, fmt.Errorf("%w: truncated tuple integer", ErrInvalidKey)
+			}
+			var buf [8]byte
+			copy(buf[8-n:], b[:n])
+			v := binary.BigEndian.Uint64(buf[:])
+			b = b[n:]
+
+			switch {
+			case neg:
+				mask := uint64(math.MaxUint64) >> (64 - 8*n)
+				t = append(t, -int64(^v&mask))
+			case v > math.MaxInt64:
+				t = append(t, v)
+			default:
+				t = append(t, int64(v))
+			}
+
+		case code == tupleFloat:
+			if len(b) < 8 {
+				return nil, nil, fmt.Errorf("%w: truncated tuple float", ErrInvalidKey)
+			}
+			bits := binary.BigEndian.Uint64(b[:8])
+			if bits&(1<<63) != 0 {
+				bits ^= 1 << 63
+			} else {
+				bits = ^bits
+			}
+			t = append(t, math.Float64frombits(bits))
+			b = b[8:]
+
+		case code == tupleFalse:
+			t = append(t, false)
+
+		case code == tupleTrue:
+			t = append(t, true)
+
+		default:
+			return nil, nil, fmt.Errorf("%w: unknown tuple element type 0x%02x", ErrInvalidKey, code)
+		}
+	}
+
+	if nested {
+		return nil, nil, fmt.Errorf("%w: unterminated nested tuple", ErrInvalidKey)
+	}
+	return t, b, nil
+}
+
+// decodeTupleBytes decodes a byte string, returning any remaining data.
+func decodeTupleBytes(b []byte) ([]byte, []byte, error) {
+	v := []byte{}
+	for {
+		n := bytes.IndexByte(b, 0x00)
+		if n < 0 {
+			return nil, nil, fmt.Errorf("%w: unterminated tuple string", ErrInvalidKey)
+		}
+		v = append(v, b[:n]...)
+		b = b[n+1:]
+
+		if len(b) > 0 && b[0] == 0xff {
+			v = append(v, 0x00)
+			b = b[1:]
+			continue
+		}
+		return v, b, nil
+	}
+}
+
+// tupleIntLength gets the number of bytes needed to encode an integer.
+func tupleIntLength(v uint64) int {
+	n := 0
+	for v > 0 {
+		n++
+		v >>= 8
+	}
+	return n
+}
+
+// TupleKey creates a key codec for tuples.
+func TupleKey() *TupleKeyCodec {
+	return &TupleKeyCodec{}
+}
@@ -0,0 +1,150 @@
+package ezdb
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTupleKey(t *testing.T) {
+	codec := TupleKey()
+
+	// Tuples in ascending order
+	tuples := []Tuple{
+		{nil},
+		{[]byte{}},
+		{[]byte{0x00}},
+		{[]byte{0x00, 0x00}},
+		{[]byte{0x01}},
+		{""},
+		{"a"},
+		{"a", nil},
+		{"a", int64(-1)},
+		{"a", int64(0)},
+		{"b"},
+		{"tenant"},
+		{"tenant", Tuple{"x", nil}},
+		{"tenant", Tuple{"x", nil, "y"}},
+		{"tenant", Tuple{"y"}},
+		{"tenant", int64(42)},
+		{"tenant", int64(42), "order", int64(1)},
+		{"tenant", int64(42), "order", int64(1000)},
+		{"tenant", int64(300)},
+		{int64(math.MinInt64)},
+		{int64(-65536)},
+		{int64(-256)},
+		{int64(-255)},
+		{int64(-1)},
+		{int64(0)},
+		{int64(1)},
+		{int64(255)},
+		{int64(256)},
+		{int64(math.MaxInt64)},
+		{uint64(math.MaxUint64)},
+		{math.Inf(-1)},
+		{-1.5},
+		{math.Copysign(0, -1)},
+		{0.0},
+		{1.5},
+		{math.Inf(1)},
+		{false},
+		{true},
+	}
+
+	encoded := []string{}
+	for _, tuple := range tuples {
+		k, err := codec.Encode(tuple)
+		if err != nil {
+			t.Errorf("failed to encode %v (%q)", tuple, err)
+			continue
+		}
+		encoded = append(encoded, k)
+
+		if decoded, err := codec.Decode(k); err != nil {
+			t.Errorf("failed to decode %v (%q)", tuple, err)
+		} else if !reflect.DeepEqual(decoded, tuple) {
+			t.Errorf("incorrect decoded tuple (expected %#v, got %#v)", tuple, decoded)
+		}
+	}
+
+	for n := 1; n < len(encoded); n++ {
+		if encoded[n-1] >= encoded[n] {
+			t.Errorf("expected %v to sort before %v", tuples[n-1], tuples[n])
+		}
+	}
+
+	ts := time.Unix(0, 1700000000000000000)
+	if k, err := codec.Encode(Tuple{"ts", ts, 8, uint8(9), float32(1.5)}); err != nil {
+		t.Errorf("failed to encode tuple with conversions (%q)", err)
+	} else if decoded, _ := codec.Decode(k); !reflect.DeepEqual(decoded, Tuple{"ts", ts.UnixNano(), int64(8), int64(9), 1.5}) {
+		t.Errorf("incorrect decoded tuple with conversions (got %#v)", decoded)
+	}
+
+	if _, err := codec.Encode(Tuple{struct{}{}}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for unsupported element (got %q)", err)
+	}
+	for _, k := range []string{"\x02abc", "\x16\x01", "\x05\x02a\x00", "\x99"} {
+		if _, err := codec.Decode(k); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey decoding %q (got %q)", k, err)
+		}
+	}
+}
+
+func TestTupleKeyPrefix(t *testing.T) {
+	path := ".leveldb/leveldb_tuple_test"
+	c := Keyed(LevelDB[*Student](path, studentMarshaler, nil), TupleKey())
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer c.Collection().(*LevelDBCollection[*Student]).Destroy()
+
+	keys := []Tuple{
+		{"tenant", 7, "order", 1},
+		{"tenant", 42, "order", 2},
+		{"tenant", 42, "order", 10},
+		{"tenant", 42, "user", 1},
+		{"tenant", 420, "order", 1},
+		{"tenants", 42},
+	}
+	for _, key := range keys {
+		if err := c.Put(key, &Student{Name: "Student"}); err != nil {
+			t.Fatalf("failed to put %v (%q)", key, err)
+		}
+	}
+
+	prefixes := map[int]Tuple{
+		5: {"tenant"},
+		3: {"tenant", 42},
+		2: {"tenant", 42, "order"},
+		1: {"tenant", 42, "order", 10},
+	}
+	for expected, prefix := range prefixes {
+		i, err := c.Prefix(prefix)
+		if err != nil {
+			t.Errorf("failed to create iterator for %v (%q)", prefix, err)
+			continue
+		}
+		if n := i.Count(); n != expected {
+			t.Errorf("incorrect count for prefix %v (expected %d, got %d)", prefix, expected, n)
+		}
+		i.Release()
+	}
+
+	i, _ := c.Prefix(Tuple{"tenant", 42, "order"})
+	defer i.Release()
+	orders := []int64{}
+	for ok := i.First(); ok; ok = i.Next() {
+		key, err := c.Key(i)
+		if err != nil {
+			t.Errorf("failed to decode key (%q)", err)
+			continue
+		}
+		orders = append(orders, key[3].(int64))
+	}
+	if !slices.Equal(orders, []int64{2, 10}) {
+		t.Errorf("incorrect orders (expected [2 10], got %v)", orders)
+	}
+}
@@ -0,0 +1,84 @@
+package ezdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Version of the unique index format in LevelDB.
+// Indexes recorded with another version are rebuilt.
+const uniqueIndexVersion byte = 1
+
+// uniqueRecordPrefix is the prefix of keys recording the unique constraints registered in LevelDB, followed by the constraint name.
+const uniqueRecordPrefix = metaPrefix + "constraint:"
+
+// uniqueConstraint requires a field to be unique among documents in a collection.
+// Documents with an empty value are not constrained.
+type uniqueConstraint[T any] struct {
+	name    string
+	version uint64
+	field   func(T) string
+
+	// Field values mapped to document keys.
+	// This is only used by MemoryCollection; LevelDBCollection stores its index in the database.
+	index map[string]string
+}
+
+// value gets the constrained field of a document, or an empty string if there is no document.
+func (u *uniqueConstraint[T]) value(doc *T) string {
+	if doc == nil {
+		return ""
+	}
+	return u.field(*doc)
+}
+
+// record gets the value recording the constraint in LevelDB, which changes if the constraint or index format changes.
+func (u *uniqueConstraint[T]) record() []byte {
+	return binary.BigEndian.AppendUint64([]byte{uniqueIndexVersion}, u.version)
+}
+
+// reindex rebuilds the index from a map of documents.
+func (u *uniqueConstraint[T]) reindex(m map[string]T) error {
+	index := map[string]string{}
+	for key, doc := range m {
+		v := u.field(doc)
+		if v == "" {
+			continue
+		}
+		if other, ok := index[v]; ok {
+			return constraintError(u.name, v, other)
+		}
+		index[v] = key
+	}
+	u.index = index
+	return nil
+}
+
+// checkConstraintName returns ErrInvalidConstraint if a constraint name is empty or contains a null byte, which separates the name from values in index keys.
+func checkConstraintName(name string) error {
+	if name == "" || strings.ContainsRune(name, 0) {
+		return fmt.Errorf("%w: name %q must not be empty or contain a null byte", ErrInvalidConstraint, name)
+	}
+	return nil
+}
+
+// constraintError creates an error for a unique value that is already used by another document.
+func constraintError(name, value, key string) error {
+	return fmt.Errorf("%w: %s %q is already used by document '%s'", ErrConstraint, name, value, key)
+}
+
+// uniqueIndexKey gets the key of a unique index entry in LevelDB.
+func uniqueIndexKey(name, value string) []byte {
+	return []byte(uniqueIndexPrefix(name) + value)
+}
+
+// uniqueRecordKey gets the key recording a unique constraint in LevelDB.
+func uniqueRecordKey(name string) []byte {
+	return []byte(uniqueRecordPrefix + name)
+}
+
+// uniqueIndexPrefix gets the prefix of all entries in a unique index in LevelDB.
+func uniqueIndexPrefix(name string) string {
+	return metaPrefix + "unique:" + name + "\x00"
+}
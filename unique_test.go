@@ -0,0 +1,190 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+func studentName(s *Student) string {
+	return s.Name
+}
+
+func TestUnique(t *testing.T) {
+	path := ".leveldb/leveldb_unique_test"
+	l := LevelDB[*Student](path, studentMarshaler, nil)
+	defer l.Destroy()
+
+	collections := map[string]interface {
+		Collection[*Student]
+		Unique(name string, f func(*Student) string) error
+	}{
+		"leveldb": l,
+		"memory":  Memory[*Student](nil),
+	}
+
+	for name, c := range collections {
+		if err := c.Open(); err != nil {
+			t.Fatalf("(%s) failed to open collection (%q)", name, err)
+		}
+		if err := c.Unique("name", studentName); err != nil {
+			t.Fatalf("(%s) failed to add constraint (%q)", name, err)
+		}
+		for _, bad := range []string{"", "bad\x00name"} {
+			if err := c.Unique(bad, studentName); !errors.Is(err, ErrInvalidConstraint) {
+				t.Errorf("(%s) expected ErrInvalidConstraint for name %q (got %q)", name, bad, err)
+			}
+		}
+
+		if err := c.Put("annie", &Student{Name: "Annie", Age: 32}); err != nil {
+			t.Errorf("(%s) failed to put student (%q)", name, err)
+		}
+		if err := c.Put("annie", &Student{Name: "Annie", Age: 33}); err != nil {
+			t.Errorf("(%s) failed to update student with the same name (%q)", name, err)
+		}
+		if err := c.Put("annie2", &Student{Name: "Annie"}); !errors.Is(err, ErrConstraint) {
+			t.Errorf("(%s) expected ErrConstraint for duplicate name (got %q)", name, err)
+		}
+		if err := c.Put("nameless", &Student{}); err != nil {
+			t.Errorf("(%s) failed to put student without a name (%q)", name, err)
+		}
+		if err := c.Put("nameless2", &Student{}); err != nil {
+			t.Errorf("(%s) failed to put second student without a name (%q)", name, err)
+		}
+
+		// Renaming or deleting a document frees its value
+		if err := c.Put("annie", &Student{Name: "Ann"}); err != nil {
+			t.Errorf("(%s) failed to rename student (%q)", name, err)
+		}
+		if err := c.Put("annie2", &Student{Name: "Annie"}); err != nil {
+			t.Errorf("(%s) failed to put student with a freed name (%q)", name, err)
+		}
+		if err := c.Delete("annie2"); err != nil {
+			t.Errorf("(%s) failed to delete student (%q)", name, err)
+		}
+		if err := c.Put("annie3", &Student{Name: "Annie"}); err != nil {
+			t.Errorf("(%s) failed to put student with a deleted name (%q)", name, err)
+		}
+
+		// Only one of several concurrent writers can succeed
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 20)
+		for n := 0; n < 20; n++ {
+			wg.Add(1)
+			go func(n int) {
+				defer wg.Done()
+				errs <- c.Put(fmt.Sprintf("ben%d", n), &Student{Name: "Ben"})
+			}(n)
+		}
+		wg.Wait()
+		close(errs)
+
+		ok := 0
+		for err := range errs {
+			if err == nil {
+				ok++
+			} else if !errors.Is(err, ErrConstraint) {
+				t.Errorf("(%s) expected ErrConstraint for concurrent put (got %q)", name, err)
+			}
+		}
+		if ok != 1 {
+			t.Errorf("(%s) expected 1 concurrent put to succeed, got %d", name, ok)
+		}
+
+		if n := c.Iter().Count(); n != 5 {
+			t.Errorf("(%s) incorrect count of students (expected 5, got %d)", name, n)
+		}
+
+		if err := c.Unique("age", func(s *Student) string { return fmt.Sprint(s.Age) }); !errors.Is(err, ErrConstraint) {
+			t.Errorf("(%s) expected ErrConstraint adding a constraint that is already violated (got %q)", name, err)
+		}
+	}
+
+	// The index is kept when the collection is reopened
+	if err := l.Close(); err != nil {
+		t.Fatalf("failed to close collection (%q)", err)
+	}
+	if err := l.Open(); err != nil {
+		t.Fatalf("failed to reopen collection (%q)", err)
+	}
+	if err := l.Put("clive", &Student{Name: "Ann"}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("expected ErrConstraint after reopening (got %q)", err)
+	}
+}
+
+func TestUniqueLevelDBReopen(t *testing.T) {
+	path := ".leveldb/leveldb_unique_reopen_test"
+	m := &countingMarshaler[*Student]{DocumentMarshaler: studentMarshaler}
+
+	open := func(version uint64) *LevelDBCollection[*Student] {
+		c := LevelDB[*Student](path, m, nil)
+		if err := c.UniqueVersion("name", version, studentName); err != nil {
+			t.Fatalf("failed to add constraint (%q)", err)
+		}
+		m.n = 0
+		if err := c.Open(); err != nil {
+			t.Fatalf("failed to open collection (%q)", err)
+		}
+		return c
+	}
+
+	c := open(0)
+	defer c.Destroy()
+	for key, value := range students {
+		if err := c.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+	c.Close()
+
+	// An unchanged constraint is not rebuilt
+	c = open(0)
+	if m.n != 0 {
+		t.Errorf("expected no students to be unmarshaled reopening an unchanged constraint, got %d", m.n)
+	}
+	if err := c.Put("clive", &Student{Name: "Annie"}); !errors.Is(err, ErrConstraint) {
+		t.Errorf("expected ErrConstraint after reopening (got %q)", err)
+	}
+	c.Close()
+
+	// A new version is rebuilt
+	c = open(1)
+	if m.n != len(students) {
+		t.Errorf("expected %d students to be unmarshaled reopening a changed constraint, got %d", len(students), m.n)
+	}
+	c.Close()
+
+	// A constraint that is no longer registered is deleted
+	c = LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection without constraint (%q)", err)
+	}
+	iter := c.db.NewIterator(util.BytesPrefix([]byte(metaPrefix)), nil)
+	for iter.Next() {
+		t.Errorf("expected constraint to be deleted, found %q", iter.Key())
+	}
+	iter.Release()
+	if err := c.Put("clive", &Student{Name: "Annie"}); err != nil {
+		t.Errorf("failed to put student with duplicate name without constraint (%q)", err)
+	}
+	c.Close()
+
+	// Open fails and closes the database if documents violate a new constraint
+	c = LevelDB[*Student](path, m, nil)
+	if err := c.Unique("name", studentName); err != nil {
+		t.Fatalf("failed to add constraint (%q)", err)
+	}
+	if err := c.Open(); !errors.Is(err, ErrConstraint) {
+		t.Errorf("expected ErrConstraint opening collection with violated constraint (got %q)", err)
+	}
+	if c.db != nil {
+		t.Error("expected database to be closed after failing to open")
+	}
+
+	if err := c.Unique("bad\x00name", studentName); !errors.Is(err, ErrInvalidConstraint) {
+		t.Errorf("expected ErrInvalidConstraint for name with null byte (got %q)", err)
+	}
+}
@@ -0,0 +1,115 @@
+package ezdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validatable is implemented by documents that can validate themselves.
+// If T implements Validatable, documents are validated before they are put into a collection.
+type Validatable interface {
+	Validate() error
+}
+
+// Validator validates documents before they are put into a collection.
+// To report problems with several fields, return a ValidationError.
+type Validator[T any] interface {
+	Validate(key string, value T) error
+}
+
+// ValidatorFunc is a function that implements Validator.
+type ValidatorFunc[T any] func(key string, value T) error
+
+// FieldError is a problem with a single field of a document.
+type FieldError struct {
+	Field string // Name of the field, or empty if the problem is not specific to a field.
+	Err   error
+}
+
+// ValidationError collects the problems found when validating a document.
+// It wraps ErrInvalidDocument as well as the error for each field.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func (f ValidatorFunc[T]) Validate(key string, value T) error {
+	return f(key, value)
+}
+
+// Add a problem with a field.
+// If err is a ValidationError, its fields are added instead.
+func (e *ValidationError) Add(field string, err error) {
+	if err == nil {
+		return
+	}
+
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		for _, f := range ve.Fields {
+			if field != "" && f.Field != "" {
+				f = &FieldError{Field: field + "." + f.Field, Err: f.Err}
+			} else if field != "" {
+				f = &FieldError{Field: field, Err: f.Err}
+			}
+			e.Fields = append(e.Fields, f)
+		}
+		return
+	}
+
+	e.Fields = append(e.Fields, &FieldError{Field: field, Err: err})
+}
+
+// Addf adds a problem with a field, formatted as by fmt.Errorf.
+func (e *ValidationError) Addf(field, format string, a ...any) {
+	e.Add(field, fmt.Errorf(format, a...))
+}
+
+// Err returns the ValidationError if any problems have been added, or nil otherwise.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for n, f := range e.Fields {
+		msgs[n] = f.Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidDocument, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{ErrInvalidDocument}
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// validate a document using its own Validate method, if any, and a validator, if any.
+func validate[T any](v Validator[T], key string, value T) error {
+	e := &ValidationError{}
+
+	if d, ok := any(value).(Validatable); ok {
+		e.Add("", d.Validate())
+	}
+	if v != nil {
+		e.Add("", v.Validate(key, value))
+	}
+
+	return e.Err()
+}
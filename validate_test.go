@@ -0,0 +1,70 @@
+package ezdb
+
+import (
+	"errors"
+	"testing"
+)
+
+var errTooYoung = errors.New("must be at least 18")
+
+// checkedStudent is a student that validates itself.
+type checkedStudent struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func (s *checkedStudent) Validate() error {
+	e := &ValidationError{}
+	if s.Name == "" {
+		e.Addf("name", "is required")
+	}
+	if s.Age < 18 {
+		e.Add("age", errTooYoung)
+	}
+	return e.Err()
+}
+
+func TestValidate(t *testing.T) {
+	path := ".leveldb/leveldb_validate_test"
+	l := LevelDB(path, JSON(func() *checkedStudent { return &checkedStudent{} }), nil)
+	defer l.Destroy()
+
+	noAdmin := ValidatorFunc[*checkedStudent](func(key string, value *checkedStudent) error {
+		if key == "admin" {
+			return errors.New("key is reserved")
+		}
+		return nil
+	})
+
+	collections := map[string]Collection[*checkedStudent]{
+		"leveldb": l.SetValidator(noAdmin),
+		"memory":  Memory[*checkedStudent](nil).SetValidator(noAdmin),
+	}
+
+	for name, c := range collections {
+		if err := c.Open(); err != nil {
+			t.Fatalf("(%s) failed to open collection (%q)", name, err)
+		}
+
+		if err := c.Put("annie", &checkedStudent{Name: "Annie", Age: 32}); err != nil {
+			t.Errorf("(%s) failed to put valid student (%q)", name, err)
+		}
+
+		err := c.Put("admin", &checkedStudent{Age: 12})
+		var ve *ValidationError
+		if !errors.As(err, &ve) {
+			t.Errorf("(%s) expected ValidationError (got %q)", name, err)
+			continue
+		}
+		if !errors.Is(err, ErrInvalidDocument) || !errors.Is(err, errTooYoung) {
+			t.Errorf("(%s) expected ValidationError to wrap ErrInvalidDocument and field errors (got %q)", name, err)
+		}
+		if len(ve.Fields) != 3 || ve.Fields[0].Field != "name" || ve.Fields[1].Field != "age" || ve.Fields[2].Field != "" {
+			t.Errorf("(%s) incorrect fields in ValidationError (got %q)", name, err)
+		}
+
+		if has, _ := c.Has("admin"); has {
+			t.Errorf("(%s) expected invalid student not to be stored", name)
+		}
+	}
+}
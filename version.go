@@ -0,0 +1,123 @@
+package ezdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Header prepended to versioned values, followed by the schema version as a uvarint.
+var versionedHeader = []byte("\x00ezv")
+
+// UpgradeFunc upgrades a marshaled document from one schema version to the next.
+// The data passed in and returned is in the format of the marshaler wrapped by a VersionedMarshaler, so VersionedMarshaler should directly wrap the marshaler that encodes documents.
+type UpgradeFunc func(src []byte) ([]byte, error)
+
+// VersionedMarshaler is a DocumentMarshaler that stores a schema version with each document and upgrades older documents when they are read.
+type VersionedMarshaler[T any] struct {
+	m        DocumentMarshaler[T, []byte]
+	version  uint64
+	upgrades map[uint64]UpgradeFunc
+}
+
+func (m *VersionedMarshaler[T]) Factory() T {
+	return m.m.Factory()
+}
+
+func (m *VersionedMarshaler[T]) Marshal(src T) ([]byte, error) {
+	raw, err := m.m.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+
+	dest := make([]byte, 0, len(versionedHeader)+binary.MaxVarintLen64+len(raw))
+	dest = append(dest, versionedHeader...)
+	dest = binary.AppendUvarint(dest, m.version)
+	dest = append(dest, raw...)
+	return dest, nil
+}
+
+func (m *VersionedMarshaler[T]) Unmarshal(src []byte, dest T) error {
+	version, raw, err := readVersion(src)
+	if err != nil {
+		return err
+	}
+	if version > m.version {
+		return fmt.Errorf("%w: version %d is newer than %d", ErrVersion, version, m.version)
+	}
+
+	for ; version < m.version; version++ {
+		upgrade, ok := m.upgrades[version]
+		if !ok {
+			return fmt.Errorf("%w: no upgrade from version %d", ErrVersion, version)
+		}
+		if raw, err = upgrade(raw); err != nil {
+			return err
+		}
+	}
+
+	return m.m.Unmarshal(raw, dest)
+}
+
+// Upgrade registers a function to upgrade documents from version from to version from+1.
+// Documents written before versioning was enabled are version 0.
+func (m *VersionedMarshaler[T]) Upgrade(from uint64, f UpgradeFunc) *VersionedMarshaler[T] {
+	m.upgrades[from] = f
+	return m
+}
+
+// Version gets the current schema version.
+func (m *VersionedMarshaler[T]) Version() uint64 {
+	return m.version
+}
+
+// MigrateAll rewrites every document in a collection, so that any upgrades performed when reading documents are stored.
+// This returns the number of documents rewritten.
+//
+// Keys are read from an iterator while documents are rewritten, so the collection's iterator should not be affected by writes to existing keys.
+// LevelDB iterators read from a snapshot, so this is safe for LevelDB collections.
+func MigrateAll[T any](c Collection[T]) (int, error) {
+	iter := c.Iter()
+
+	n := 0
+	for key := range iter.Keys() {
+		value, err := c.Get(key)
+		if err != nil {
+			return n, &DocumentError{Key: key, Err: err}
+		}
+		if err := c.Put(key, value); err != nil {
+			return n, &DocumentError{Key: key, Err: err}
+		}
+		n++
+	}
+	return n, iter.Err()
+}
+
+// Versioned creates a DocumentMarshaler that stores the schema version with each document marshaled by m.
+//
+// When a document with an older version is read, upgrade functions are run in sequence to bring it to the current version before it is passed to m.
+// Register upgrade functions with Upgrade.
+//
+// Upgrade functions receive the output of m, so if combining with other marshalers such as Compressed or Encrypted, m should not be one of them.
+// Wrap the VersionedMarshaler instead, e.g. Encrypted(Compressed(Versioned(m, version), codec), keys).
+func Versioned[T any](m DocumentMarshaler[T, []byte], version uint64) *VersionedMarshaler[T] {
+	return &VersionedMarshaler[T]{
+		m:        m,
+		version:  version,
+		upgrades: map[uint64]UpgradeFunc{},
+	}
+}
+
+// readVersion reads the schema version from a value, returning the version and remaining data.
+// Unversioned values are version 0.
+func readVersion(src []byte) (uint64, []byte, error) {
+	if !bytes.HasPrefix(src, versionedHeader) {
+		return 0, src, nil
+	}
+
+	version, n := binary.Uvarint(src[len(versionedHeader):])
+	if n <= 0 {
+		return 0, nil, fmt.Errorf("%w: malformed version header", ErrVersion)
+	}
+	return version, src[len(versionedHeader)+n:], nil
+}
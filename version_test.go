@@ -0,0 +1,113 @@
+package ezdb
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Upgrade that renames the legacy "fullName" field to "name".
+func upgradeStudentName(src []byte) ([]byte, error) {
+	doc := map[string]any{}
+	if err := json.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	doc["name"] = doc["fullName"]
+	delete(doc, "fullName")
+	return json.Marshal(doc)
+}
+
+// Upgrade that sets a default age.
+func upgradeStudentAge(src []byte) ([]byte, error) {
+	doc := map[string]any{}
+	if err := json.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	if _, ok := doc["age"]; !ok {
+		doc["age"] = 18
+	}
+	return json.Marshal(doc)
+}
+
+func TestVersionedUpgrade(t *testing.T) {
+	m := Versioned(studentMarshaler, 2).
+		Upgrade(0, upgradeStudentName).
+		Upgrade(1, upgradeStudentAge)
+
+	legacy := []byte(`{"fullName":"Annie"}`)
+	actual := m.Factory()
+	if err := m.Unmarshal(legacy, actual); err != nil {
+		t.Fatalf("failed to unmarshal legacy student (%q)", err)
+	}
+	if err := compareStudent("annie", &Student{Name: "Annie", Age: 18}, actual); err != nil {
+		t.Error(err)
+	}
+
+	b, err := m.Marshal(actual)
+	if err != nil {
+		t.Fatalf("failed to marshal student (%q)", err)
+	}
+	if version, _, err := readVersion(b); err != nil {
+		t.Errorf("failed to read version (%q)", err)
+	} else if version != 2 {
+		t.Errorf("incorrect version (expected 2, got %d)", version)
+	}
+
+	// Older marshalers cannot read newer documents
+	old := Versioned(studentMarshaler, 1)
+	if err := old.Unmarshal(b, old.Factory()); !errors.Is(err, ErrVersion) {
+		t.Errorf("expected version error, got %v", err)
+	}
+}
+
+func TestMigrateAll(t *testing.T) {
+	path := ".leveldb/version_test"
+	legacy := LevelDB[*Student](path, studentMarshaler, nil)
+	if err := legacy.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer legacy.Destroy()
+
+	for key, value := range students {
+		if err := legacy.Put(key, value); err != nil {
+			t.Fatalf("failed to put student '%s' (%q)", key, err)
+		}
+	}
+
+	if err := legacy.Close(); err != nil {
+		t.Fatalf("failed to close collection (%q)", err)
+	}
+
+	m := Versioned(studentMarshaler, 1).Upgrade(0, upgradeStudentAge)
+	c := LevelDB[*Student](path, m, nil)
+	if err := c.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+
+	n, err := MigrateAll[*Student](c)
+	if err != nil {
+		t.Fatalf("failed to migrate students (%q)", err)
+	} else if n != len(students) {
+		t.Errorf("incorrect number of students migrated (expected %d, got %d)", len(students), n)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("failed to close collection (%q)", err)
+	}
+
+	// Migrated students can be read without upgrading
+	current := LevelDB[*Student](path, Versioned(studentMarshaler, 1), nil)
+	if err := current.Open(); err != nil {
+		t.Fatalf("failed to open collection (%q)", err)
+	}
+	defer current.Close()
+
+	for key, expected := range students {
+		actual, err := current.Get(key)
+		if err != nil {
+			t.Errorf("failed to get migrated student '%s' (%q)", key, err)
+		} else if err := compareStudent(key, expected, actual); err != nil {
+			t.Error(err)
+		}
+	}
+}